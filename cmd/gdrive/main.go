@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/imzza/gdrive/internal/cli"
+	"github.com/imzza/gdrive/internal/drive"
 	"github.com/imzza/gdrive/internal/handlers"
 	"github.com/imzza/gdrive/internal/utils"
 )
@@ -18,7 +19,10 @@ const DefaultNameWidth = 40
 const DefaultPathWidth = 60
 const DefaultUploadChunkSize = 8 * 1024 * 1024
 const DefaultTimeout = 5 * 60
-const DefaultQuery = "trashed = false and 'me' in owners"
+const DefaultWorkers = 1
+const DefaultWatchDelay = 2
+const DefaultReconcileInterval = 10 * 60
+const DefaultChangesInterval = 30
 const DefaultShareRole = "reader"
 const DefaultShareType = "anyone"
 
@@ -47,6 +51,84 @@ func main() {
 			Patterns:    []string{"--service-account"},
 			Description: "Oauth service account filename, used for server to server communication without user interaction (filename path is relative to config dir)",
 		},
+		cli.StringFlag{
+			Name:        "driveId",
+			Patterns:    []string{"--drive"},
+			Description: "Id of a shared drive to work in, see 'drives list'. Files are created in the root of the shared drive when no parent is given",
+		},
+		cli.StringFlag{
+			Name:        "keyFile",
+			Patterns:    []string{"--key-file"},
+			Description: "File holding the key used to encrypt and decrypt files, the key can also be derived from a passphrase in " + drive.PassphraseEnv,
+		},
+		cli.StringFlag{
+			Name:         "output",
+			Patterns:     []string{"--output"},
+			Description:  "Output format of listings: table, json, ndjson or csv, default: table",
+			DefaultValue: "table",
+		},
+	}
+
+	// Search options that are compiled into a files query
+	queryFlags := []cli.Flag{
+		cli.StringFlag{
+			Name:        "name",
+			Patterns:    []string{"--name"},
+			Description: "Only files with exactly this name",
+		},
+		cli.StringFlag{
+			Name:        "nameContains",
+			Patterns:    []string{"--name-contains"},
+			Description: "Only files with a name containing this text",
+		},
+		cli.StringFlag{
+			Name:        "parent",
+			Patterns:    []string{"--parent"},
+			Description: "Only files in this directory, id or path",
+		},
+		cli.StringFlag{
+			Name:        "type",
+			Patterns:    []string{"--type"},
+			Description: "Only files of this type: dir, file, doc, sheet, slides or shortcut",
+		},
+		cli.StringFlag{
+			Name:        "modifiedAfter",
+			Patterns:    []string{"--modified-after"},
+			Description: "Only files modified after this date (2006-01-02) or time (2006-01-02T15:04:05Z)",
+		},
+		cli.StringFlag{
+			Name:        "largerThan",
+			Patterns:    []string{"--larger-than"},
+			Description: "Only files larger than this size, e.g. 500K, 10M or 1G",
+		},
+		cli.BoolFlag{
+			Name:        "starred",
+			Patterns:    []string{"--starred"},
+			Description: "Only starred files",
+			OmitValue:   true,
+		},
+		cli.BoolFlag{
+			Name:        "sharedWithMe",
+			Patterns:    []string{"--shared-with-me"},
+			Description: "Only files shared with me",
+			OmitValue:   true,
+		},
+		cli.BoolFlag{
+			Name:        "trashed",
+			Patterns:    []string{"--trashed"},
+			Description: "Only trashed files, trashed files are left out otherwise",
+			OmitValue:   true,
+		},
+		cli.StringFlag{
+			Name:        "fullText",
+			Patterns:    []string{"--full-text"},
+			Description: "Only files with this text in the name, description or content",
+		},
+		cli.StringFlag{
+			Name:        "owner",
+			Patterns:    []string{"--owner"},
+			Description: "Only files owned by this email address, use 'me' for your own files",
+		},
 	}
 
 	handlers.AppName = Name
@@ -165,7 +247,7 @@ func main() {
 			Callback:    handlers.ListHandler,
 			FlagGroups: cli.FlagGroups{
 				cli.NewFlagGroup("global", globalFlags...),
-				cli.NewFlagGroup("options",
+				cli.NewFlagGroup("options", append([]cli.Flag{
 					cli.IntFlag{
 						Name:         "maxFiles",
 						Patterns:     []string{"-m", "--max"},
@@ -173,10 +255,9 @@ func main() {
 						DefaultValue: DefaultMaxFiles,
 					},
 					cli.StringFlag{
-						Name:         "query",
-						Patterns:     []string{"-q", "--query"},
-						Description:  fmt.Sprintf(`Default query: "%s". See https://developers.google.com/drive/search-parameters`, DefaultQuery),
-						DefaultValue: DefaultQuery,
+						Name:        "query",
+						Patterns:    []string{"-q", "--query"},
+						Description: fmt.Sprintf(`Raw query that is combined with the other search options. Default query: "%s". See https://developers.google.com/drive/search-parameters`, drive.DefaultQuery),
 					},
 					cli.StringFlag{
 						Name:        "sortOrder",
@@ -207,7 +288,12 @@ func main() {
 						Description: "Size in bytes",
 						OmitValue:   true,
 					},
-				),
+					cli.StringFlag{
+						Name:        "format",
+						Patterns:    []string{"--format"},
+						Description: "Format output using a Go template, e.g. '{{.Id}} {{.Name}} {{size .Size}}'. Available functions: size, bytes, datetime, path, join and json",
+					},
+				}, queryFlags...)...),
 			},
 		},
 		{
@@ -242,6 +328,38 @@ func main() {
 				cli.NewFlagGroup("global", globalFlags...),
 			},
 		},
+		{
+			Pattern:     "[global] files trash help",
+			Description: "Print command help",
+			Callback:    handlers.FilesTrashHelpHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		{
+			Pattern:     "[global] files trash --help",
+			Description: "Print command help",
+			Callback:    handlers.FilesTrashHelpHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		{
+			Pattern:     "[global] files shortcut help",
+			Description: "Print command help",
+			Callback:    handlers.FilesShortcutHelpHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		{
+			Pattern:     "[global] files shortcut --help",
+			Description: "Print command help",
+			Callback:    handlers.FilesShortcutHelpHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
 		{
 			Pattern:     "[global] files <subcommand> help",
 			Description: "Print command help",
@@ -274,6 +392,38 @@ func main() {
 				cli.NewFlagGroup("global", globalFlags...),
 			},
 		},
+		{
+			Pattern:     "[global] files trash <subcommand> help",
+			Description: "Print command help",
+			Callback:    handlers.FilesTrashSubcommandHelpHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		{
+			Pattern:     "[global] files trash <subcommand> --help",
+			Description: "Print command help",
+			Callback:    handlers.FilesTrashSubcommandHelpHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		{
+			Pattern:     "[global] files shortcut <subcommand> help",
+			Description: "Print command help",
+			Callback:    handlers.FilesShortcutSubcommandHelpHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		{
+			Pattern:     "[global] files shortcut <subcommand> --help",
+			Description: "Print command help",
+			Callback:    handlers.FilesShortcutSubcommandHelpHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
 		{
 			Pattern:     "[global] files download [options] <fileId>",
 			Description: "Download file or directory",
@@ -305,6 +455,17 @@ func main() {
 						Description: "Download directory contents into path without creating the top-level directory",
 						OmitValue:   true,
 					},
+					cli.StringFlag{
+						Name:        "archive",
+						Patterns:    []string{"--archive"},
+						Description: "Download a directory as a single archive: tar, tar.gz or zip. Requires --recursive, use --stdout to stream the archive",
+					},
+					cli.BoolFlag{
+						Name:        "exportDocs",
+						Patterns:    []string{"--export-docs"},
+						Description: "Export google documents into the archive with their default export mime type",
+						OmitValue:   true,
+					},
 					cli.StringFlag{
 						Name:        "path",
 						Patterns:    []string{"--path"},
@@ -313,7 +474,7 @@ func main() {
 					cli.BoolFlag{
 						Name:        "delete",
 						Patterns:    []string{"--delete"},
-						Description: "Delete remote file when download is successful",
+						Description: "Move remote file to the trash when download is successful",
 						OmitValue:   true,
 					},
 					cli.BoolFlag{
@@ -328,12 +489,36 @@ func main() {
 						Description: "Write file content to stdout",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "verify",
+						Patterns:    []string{"--verify"},
+						Description: "Compare the downloaded content with the checksums drive has for the file",
+						OmitValue:   true,
+					},
 					cli.IntFlag{
 						Name:         "timeout",
 						Patterns:     []string{"--timeout"},
 						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
 						DefaultValue: DefaultTimeout,
 					},
+					cli.IntFlag{
+						Name:         "workers",
+						Patterns:     []string{"--workers"},
+						Description:  fmt.Sprintf("Number of files to download concurrently, default: %d", DefaultWorkers),
+						DefaultValue: DefaultWorkers,
+					},
+					cli.BoolFlag{
+						Name:        "permanent",
+						Patterns:    []string{"--permanent"},
+						Description: "Delete the remote file permanently instead of moving it to the trash, used together with --delete",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "followShortcuts",
+						Patterns:    []string{"--follow-shortcuts"},
+						Description: "Download the targets of shortcuts, shortcuts are skipped otherwise",
+						OmitValue:   true,
+					},
 				),
 			},
 		},
@@ -343,7 +528,7 @@ func main() {
 			Callback:    handlers.DownloadQueryHandler,
 			FlagGroups: cli.FlagGroups{
 				cli.NewFlagGroup("global", globalFlags...),
-				cli.NewFlagGroup("options",
+				cli.NewFlagGroup("options", append([]cli.Flag{
 					cli.BoolFlag{
 						Name:        "force",
 						Patterns:    []string{"-f", "--force"},
@@ -373,7 +558,25 @@ func main() {
 						Description: "Hide progress",
 						OmitValue:   true,
 					},
-				),
+					cli.BoolFlag{
+						Name:        "verify",
+						Patterns:    []string{"--verify"},
+						Description: "Compare the downloaded content with the checksums drive has for the file",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "workers",
+						Patterns:     []string{"--workers"},
+						Description:  fmt.Sprintf("Number of files to download concurrently, default: %d", DefaultWorkers),
+						DefaultValue: DefaultWorkers,
+					},
+					cli.BoolFlag{
+						Name:        "followShortcuts",
+						Patterns:    []string{"--follow-shortcuts"},
+						Description: "Download the targets of shortcuts, shortcuts are skipped otherwise",
+						OmitValue:   true,
+					},
+				}, queryFlags...)...),
 			},
 		},
 		{
@@ -389,10 +592,15 @@ func main() {
 						Description: "Upload directory recursively",
 						OmitValue:   true,
 					},
+					cli.StringFlag{
+						Name:        "archive",
+						Patterns:    []string{"--archive"},
+						Description: "Upload a directory as a single archive streamed while it is written: tar, tar.gz or zip. Files matched by a .gdriveignore in the directory are left out",
+					},
 					cli.StringSliceFlag{
 						Name:        "parent",
 						Patterns:    []string{"-p", "--parent"},
-						Description: "Parent id, used to upload file to a specific directory, can be specified multiple times to give many parents",
+						Description: "Parent id or path, used to upload file to a specific directory, can be specified multiple times to give many parents",
 					},
 					cli.StringFlag{
 						Name:        "name",
@@ -427,6 +635,24 @@ func main() {
 						Description: "Delete local file when upload is successful",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "encrypt",
+						Patterns:    []string{"--encrypt"},
+						Description: "Encrypt the content before it is uploaded, requires --key-file or " + drive.PassphraseEnv,
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "encryptNames",
+						Patterns:    []string{"--encrypt-names"},
+						Description: "Also encrypt the names of files and directories, requires --encrypt",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "verify",
+						Patterns:    []string{"--verify"},
+						Description: "Compare the uploaded content with the checksums drive computed for the file",
+						OmitValue:   true,
+					},
 					cli.IntFlag{
 						Name:         "timeout",
 						Patterns:     []string{"--timeout"},
@@ -452,7 +678,7 @@ func main() {
 					cli.StringSliceFlag{
 						Name:        "parent",
 						Patterns:    []string{"-p", "--parent"},
-						Description: "Parent id, used to upload file to a specific directory, can be specified multiple times to give many parents",
+						Description: "Parent id or path, used to upload file to a specific directory, can be specified multiple times to give many parents",
 					},
 					cli.IntFlag{
 						Name:         "chunksize",
@@ -476,6 +702,24 @@ func main() {
 						Description: "Share file",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "encrypt",
+						Patterns:    []string{"--encrypt"},
+						Description: "Encrypt the content before it is uploaded, requires --key-file or " + drive.PassphraseEnv,
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "encryptNames",
+						Patterns:    []string{"--encrypt-names"},
+						Description: "Also encrypt the filenames, requires --encrypt",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "verify",
+						Patterns:    []string{"--verify"},
+						Description: "Compare the uploaded content with the checksums drive computed for the file",
+						OmitValue:   true,
+					},
 					cli.IntFlag{
 						Name:         "timeout",
 						Patterns:     []string{"--timeout"},
@@ -501,7 +745,7 @@ func main() {
 					cli.StringSliceFlag{
 						Name:        "parent",
 						Patterns:    []string{"-p", "--parent"},
-						Description: "Parent id, used to upload file to a specific directory, can be specified multiple times to give many parents",
+						Description: "Parent id or path, used to upload file to a specific directory, can be specified multiple times to give many parents",
 					},
 					cli.StringFlag{
 						Name:        "name",
@@ -524,6 +768,24 @@ func main() {
 						Patterns:    []string{"--mime"},
 						Description: "Force mime type",
 					},
+					cli.BoolFlag{
+						Name:        "encrypt",
+						Patterns:    []string{"--encrypt"},
+						Description: "Encrypt the content before it is uploaded, requires --key-file or " + drive.PassphraseEnv,
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "encryptNames",
+						Patterns:    []string{"--encrypt-names"},
+						Description: "Also encrypt the filenames, requires --encrypt",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "verify",
+						Patterns:    []string{"--verify"},
+						Description: "Compare the uploaded content with the checksums drive computed for the file",
+						OmitValue:   true,
+					},
 					cli.IntFlag{
 						Name:         "timeout",
 						Patterns:     []string{"--timeout"},
@@ -552,6 +814,11 @@ func main() {
 						Description: "Show size in bytes",
 						OmitValue:   true,
 					},
+					cli.StringFlag{
+						Name:        "format",
+						Patterns:    []string{"--format"},
+						Description: "Format output using a Go template, e.g. '{{.Id}} {{.Name}} {{size .Size}}'. Available functions: size, bytes, datetime, path, join and json",
+					},
 				),
 			},
 		},
@@ -565,7 +832,7 @@ func main() {
 					cli.StringSliceFlag{
 						Name:        "parent",
 						Patterns:    []string{"-p", "--parent"},
-						Description: "Parent id of created directory, can be specified multiple times to give many parents",
+						Description: "Parent id or path of created directory, can be specified multiple times to give many parents",
 					},
 					cli.StringFlag{
 						Name:        "description",
@@ -600,11 +867,46 @@ func main() {
 			},
 		},
 		{
-			Pattern:     "[global] files copy <fileId> <folderId>",
-			Description: "Copy file",
+			Pattern:     "[global] files copy [options] <fileId> <folderId>",
+			Description: "Copy file or directory",
 			Callback:    handlers.CopyHandler,
 			FlagGroups: cli.FlagGroups{
 				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.BoolFlag{
+						Name:        "recursive",
+						Patterns:    []string{"-r", "--recursive"},
+						Description: "Copy directory and all it's content, a list of the old and new ids is printed at the end",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "preserve",
+						Patterns:    []string{"--preserve"},
+						Description: "Preserve descriptions and properties of the copied files",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "skipHeader",
+						Patterns:    []string{"--no-header"},
+						Description: "Dont print the header of the id list",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		{
+			Pattern:     "[global] files shortcut create [options] <targetId> <folderId>",
+			Description: "Create shortcut to file or directory",
+			Callback:    handlers.CreateShortcutHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "name",
+						Patterns:    []string{"--name"},
+						Description: "Shortcut name, defaults to the name of the target",
+					},
+				),
 			},
 		},
 		{
@@ -652,11 +954,18 @@ func main() {
 			},
 		},
 		{
-			Pattern:     "[global] permissions list <fileId>",
+			Pattern:     "[global] permissions list [options] <fileId>",
 			Description: "List files permissions",
 			Callback:    handlers.ShareListHandler,
 			FlagGroups: cli.FlagGroups{
 				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "format",
+						Patterns:    []string{"--format"},
+						Description: "Format output using a Go template, e.g. '{{.Id}} {{.Role}} {{.EmailAddress}}'. Available functions: size, bytes, datetime, path, join and json",
+					},
+				),
 			},
 		},
 		{
@@ -688,6 +997,150 @@ func main() {
 						Description: "Delete directory and all it's content",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "permanent",
+						Patterns:    []string{"--permanent"},
+						Description: "Delete permanently instead of moving to the trash",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		{
+			Pattern:     "[global] files trash list [options]",
+			Description: "List trashed files",
+			Callback:    handlers.ListTrashHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.IntFlag{
+						Name:         "maxFiles",
+						Patterns:     []string{"-m", "--max"},
+						Description:  fmt.Sprintf("Max files to list, default: %d", DefaultMaxFiles),
+						DefaultValue: DefaultMaxFiles,
+					},
+					cli.IntFlag{
+						Name:         "nameWidth",
+						Patterns:     []string{"--name-width"},
+						Description:  fmt.Sprintf("Width of name column, default: %d, minimum: 9, use 0 for full width", DefaultNameWidth),
+						DefaultValue: DefaultNameWidth,
+					},
+					cli.BoolFlag{
+						Name:        "skipHeader",
+						Patterns:    []string{"--no-header"},
+						Description: "Dont print the header",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "sizeInBytes",
+						Patterns:    []string{"--bytes"},
+						Description: "Size in bytes",
+						OmitValue:   true,
+					},
+					cli.StringFlag{
+						Name:        "format",
+						Patterns:    []string{"--format"},
+						Description: "Format output using a Go template, e.g. '{{.Id}} {{.Name}} {{size .Size}}'. Available functions: size, bytes, datetime, path, join and json",
+					},
+				),
+			},
+		},
+		{
+			Pattern:     "[global] files trash empty [options]",
+			Description: "Permanently delete all trashed files",
+			Callback:    handlers.EmptyTrashHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.BoolFlag{
+						Name:        "force",
+						Patterns:    []string{"-f", "--force"},
+						Description: "Confirm that all trashed files should be deleted permanently",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		{
+			Pattern:     "[global] files trash <fileId>",
+			Description: "Move file or directory to the trash",
+			Callback:    handlers.TrashHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		{
+			Pattern:     "[global] files untrash <fileId>",
+			Description: "Restore file or directory from the trash",
+			Callback:    handlers.UntrashHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		{
+			Pattern:     "[global] files dedupe [options]",
+			Description: "Find files with the same content or the same name in a directory, and trash the extra copies",
+			Callback:    handlers.DedupeHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options", append([]cli.Flag{
+					cli.StringFlag{
+						Name:        "query",
+						Patterns:    []string{"-q", "--query"},
+						Description: fmt.Sprintf(`Raw query that is combined with the other search options. Default query: "%s". See https://developers.google.com/drive/search-parameters`, drive.DefaultQuery),
+					},
+					cli.StringFlag{
+						Name:         "keep",
+						Patterns:     []string{"--keep"},
+						Description:  "Which file of each group to keep: oldest, newest, or a path to keep the oldest file at or below it, default: oldest",
+						DefaultValue: drive.DedupeKeepOldest,
+					},
+					cli.BoolFlag{
+						Name:        "trash",
+						Patterns:    []string{"--trash"},
+						Description: "Trash all but the kept file of each group with the same content, files with only the same name are never trashed",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "skipHeader",
+						Patterns:    []string{"--no-header"},
+						Description: "Dont print the header",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "sizeInBytes",
+						Patterns:    []string{"--bytes"},
+						Description: "Size in bytes",
+						OmitValue:   true,
+					},
+				}, queryFlags...)...),
+			},
+		},
+		{
+			Pattern:     "[global] files du [options] <fileId>",
+			Description: "Show the size of a directory and its biggest directories and files",
+			Callback:    handlers.DiskUsageHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.IntFlag{
+						Name:         "depth",
+						Patterns:     []string{"--depth"},
+						Description:  "Show directories and files down to this depth below the directory, default: 1, use -1 to show all",
+						DefaultValue: 1,
+					},
+					cli.BoolFlag{
+						Name:        "skipHeader",
+						Patterns:    []string{"--no-header"},
+						Description: "Dont print the header",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "sizeInBytes",
+						Patterns:    []string{"--bytes"},
+						Description: "Size in bytes",
+						OmitValue:   true,
+					},
 				),
 			},
 		},
@@ -783,12 +1236,30 @@ func main() {
 						Description: "Hide progress",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "noVerify",
+						Patterns:    []string{"--no-verify"},
+						Description: "Do not compare the transferred content with the checksums drive has for the file",
+						OmitValue:   true,
+					},
 					cli.IntFlag{
 						Name:         "timeout",
 						Patterns:     []string{"--timeout"},
 						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
 						DefaultValue: DefaultTimeout,
 					},
+					cli.IntFlag{
+						Name:         "workers",
+						Patterns:     []string{"--workers"},
+						Description:  fmt.Sprintf("Number of files to download concurrently, default: %d", DefaultWorkers),
+						DefaultValue: DefaultWorkers,
+					},
+					cli.BoolFlag{
+						Name:        "fullScan",
+						Patterns:    []string{"--full-scan"},
+						Description: "List all remote files instead of only reading the changes since the last sync",
+						OmitValue:   true,
+					},
 				),
 			},
 		},
@@ -820,7 +1291,113 @@ func main() {
 					cli.BoolFlag{
 						Name:        "deleteExtraneous",
 						Patterns:    []string{"--delete-extraneous"},
-						Description: "Delete extraneous remote files",
+						Description: "Move extraneous remote files to the trash",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "dryRun",
+						Patterns:    []string{"--dry-run"},
+						Description: "Show what would have been transferred",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "noProgress",
+						Patterns:    []string{"--no-progress"},
+						Description: "Hide progress",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "encrypt",
+						Patterns:    []string{"--encrypt"},
+						Description: "Encrypt the content before it is uploaded, requires --key-file or " + drive.PassphraseEnv,
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "encryptNames",
+						Patterns:    []string{"--encrypt-names"},
+						Description: "Also encrypt the names of files and directories, requires --encrypt",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "noVerify",
+						Patterns:    []string{"--no-verify"},
+						Description: "Do not compare the transferred content with the checksums drive has for the file",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "timeout",
+						Patterns:     []string{"--timeout"},
+						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
+						DefaultValue: DefaultTimeout,
+					},
+					cli.IntFlag{
+						Name:         "chunksize",
+						Patterns:     []string{"--chunksize"},
+						Description:  fmt.Sprintf("Set chunk size in bytes, default: %d", DefaultUploadChunkSize),
+						DefaultValue: DefaultUploadChunkSize,
+					},
+					cli.IntFlag{
+						Name:         "workers",
+						Patterns:     []string{"--workers"},
+						Description:  fmt.Sprintf("Number of files to upload concurrently, default: %d", DefaultWorkers),
+						DefaultValue: DefaultWorkers,
+					},
+					cli.BoolFlag{
+						Name:        "fullScan",
+						Patterns:    []string{"--full-scan"},
+						Description: "List all remote files instead of only reading the changes since the last sync",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "watch",
+						Patterns:    []string{"--watch"},
+						Description: "Keep running and upload local changes as they happen, only supported on linux",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "watchDelay",
+						Patterns:     []string{"--watch-delay"},
+						Description:  fmt.Sprintf("Seconds to wait for more changes before uploading, default: %d", DefaultWatchDelay),
+						DefaultValue: DefaultWatchDelay,
+					},
+					cli.IntFlag{
+						Name:         "reconcileInterval",
+						Patterns:     []string{"--reconcile-interval"},
+						Description:  fmt.Sprintf("Seconds between full syncs when watching, use 0 to disable, default: %d", DefaultReconcileInterval),
+						DefaultValue: DefaultReconcileInterval,
+					},
+					cli.BoolFlag{
+						Name:        "permanent",
+						Patterns:    []string{"--permanent"},
+						Description: "Delete extraneous remote files permanently instead of moving them to the trash",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		{
+			Pattern:     "[global] files sync both [options] <path> <fileId>",
+			Description: "Sync changes in both directions between local directory and drive",
+			Callback:    handlers.BidirectionalSyncHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.BoolFlag{
+						Name:        "keepRemote",
+						Patterns:    []string{"--keep-remote"},
+						Description: "Keep remote file when a conflict is encountered",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "keepLocal",
+						Patterns:    []string{"--keep-local"},
+						Description: "Keep local file when a conflict is encountered",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "keepLargest",
+						Patterns:    []string{"--keep-largest"},
+						Description: "Keep largest file when a conflict is encountered",
 						OmitValue:   true,
 					},
 					cli.BoolFlag{
@@ -835,6 +1412,12 @@ func main() {
 						Description: "Hide progress",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "noVerify",
+						Patterns:    []string{"--no-verify"},
+						Description: "Do not compare the transferred content with the checksums drive has for the file",
+						OmitValue:   true,
+					},
 					cli.IntFlag{
 						Name:         "timeout",
 						Patterns:     []string{"--timeout"},
@@ -847,6 +1430,24 @@ func main() {
 						Description:  fmt.Sprintf("Set chunk size in bytes, default: %d", DefaultUploadChunkSize),
 						DefaultValue: DefaultUploadChunkSize,
 					},
+					cli.IntFlag{
+						Name:         "workers",
+						Patterns:     []string{"--workers"},
+						Description:  fmt.Sprintf("Number of files to transfer concurrently, default: %d", DefaultWorkers),
+						DefaultValue: DefaultWorkers,
+					},
+					cli.BoolFlag{
+						Name:        "fullScan",
+						Patterns:    []string{"--full-scan"},
+						Description: "List all remote files instead of only reading the changes since the last sync",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "permanent",
+						Patterns:    []string{"--permanent"},
+						Description: "Delete remote files permanently instead of moving them to the trash",
+						OmitValue:   true,
+					},
 				),
 			},
 		},
@@ -864,10 +1465,9 @@ func main() {
 						DefaultValue: DefaultMaxChanges,
 					},
 					cli.StringFlag{
-						Name:         "pageToken",
-						Patterns:     []string{"--since"},
-						Description:  fmt.Sprint("Page token to start listing changes from"),
-						DefaultValue: "1",
+						Name:        "pageToken",
+						Patterns:    []string{"--since"},
+						Description: fmt.Sprint("Page token to start listing changes from, default: 1, or the saved token when following changes"),
 					},
 					cli.BoolFlag{
 						Name:        "now",
@@ -887,6 +1487,33 @@ func main() {
 						Description: "Dont print the header",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "follow",
+						Patterns:    []string{"--follow"},
+						Description: "Keep polling for new changes and print them as they arrive",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "interval",
+						Patterns:     []string{"--interval"},
+						Description:  fmt.Sprintf("Seconds between polls when following changes, at least 1, default: %d", DefaultChangesInterval),
+						DefaultValue: DefaultChangesInterval,
+					},
+					cli.StringFlag{
+						Name:        "stateFile",
+						Patterns:    []string{"--state-file"},
+						Description: "File where the last page token is saved when following changes, default: changes_state.json in the config dir",
+					},
+					cli.StringFlag{
+						Name:        "command",
+						Patterns:    []string{"--exec"},
+						Description: "Command to run for every change when following changes. GDRIVE_FILE_ID, GDRIVE_FILE_NAME, GDRIVE_ACTION, GDRIVE_MIME_TYPE and GDRIVE_CHANGE_TIME are set in its environment",
+					},
+					cli.StringFlag{
+						Name:        "format",
+						Patterns:    []string{"--format"},
+						Description: "Format output using a Go template, e.g. '{{.FileId}} {{.File.Name}} {{.Time}}'. Available functions: size, bytes, datetime, path, join and json",
+					},
 				),
 			},
 		},
@@ -915,6 +1542,11 @@ func main() {
 						Description: "Size in bytes",
 						OmitValue:   true,
 					},
+					cli.StringFlag{
+						Name:        "format",
+						Patterns:    []string{"--format"},
+						Description: "Format output using a Go template, e.g. '{{.Id}} {{.OriginalFilename}} {{datetime .ModifiedTime}}'. Available functions: size, bytes, datetime, path, join and json",
+					},
 				),
 			},
 		},
@@ -975,7 +1607,7 @@ func main() {
 					cli.StringSliceFlag{
 						Name:        "parent",
 						Patterns:    []string{"-p", "--parent"},
-						Description: "Parent id, used to upload file to a specific directory, can be specified multiple times to give many parents",
+						Description: "Parent id or path, used to upload file to a specific directory, can be specified multiple times to give many parents",
 					},
 					cli.BoolFlag{
 						Name:        "noProgress",
@@ -1034,6 +1666,112 @@ func main() {
 				),
 			},
 		},
+		{
+			Pattern:     "[global] serve webdav [options]",
+			Description: "Serve a folder over WebDAV",
+			Callback:    handlers.ServeWebdavHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "root",
+						Patterns:    []string{"--root"},
+						Description: "Id or path of the folder to serve, default: the root of the drive",
+					},
+					cli.StringFlag{
+						Name:         "addr",
+						Patterns:     []string{"--addr"},
+						Description:  fmt.Sprintf("Address to listen on, default: %s", drive.DefaultServeAddr),
+						DefaultValue: drive.DefaultServeAddr,
+					},
+					cli.StringFlag{
+						Name:        "auth",
+						Patterns:    []string{"--auth"},
+						Description: "Require basic authentication with the given user:password",
+					},
+					cli.IntFlag{
+						Name:         "cacheTtl",
+						Patterns:     []string{"--cache-ttl"},
+						Description:  fmt.Sprintf("Seconds directory listings are cached, use 0 for no caching, default: %d", drive.DefaultServeCacheTtl),
+						DefaultValue: drive.DefaultServeCacheTtl,
+					},
+					cli.IntFlag{
+						Name:         "chunksize",
+						Patterns:     []string{"--chunksize"},
+						Description:  fmt.Sprintf("Set upload chunk size in bytes, default: %d", DefaultUploadChunkSize),
+						DefaultValue: DefaultUploadChunkSize,
+					},
+					cli.BoolFlag{
+						Name:        "permanent",
+						Patterns:    []string{"--permanent"},
+						Description: "Delete files permanently instead of moving them to the trash",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		{
+			Pattern:     "[global] serve http [options]",
+			Description: "Serve a folder read only over HTTP",
+			Callback:    handlers.ServeHttpHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "root",
+						Patterns:    []string{"--root"},
+						Description: "Id or path of the folder to serve, default: the root of the drive",
+					},
+					cli.StringFlag{
+						Name:         "addr",
+						Patterns:     []string{"--addr"},
+						Description:  fmt.Sprintf("Address to listen on, default: %s", drive.DefaultServeAddr),
+						DefaultValue: drive.DefaultServeAddr,
+					},
+					cli.StringFlag{
+						Name:        "auth",
+						Patterns:    []string{"--auth"},
+						Description: "Require basic authentication with the given user:password",
+					},
+					cli.IntFlag{
+						Name:         "cacheTtl",
+						Patterns:     []string{"--cache-ttl"},
+						Description:  fmt.Sprintf("Seconds directory listings are cached, use 0 for no caching, default: %d", drive.DefaultServeCacheTtl),
+						DefaultValue: drive.DefaultServeCacheTtl,
+					},
+				),
+			},
+		},
+		{
+			Pattern:     "[global] serve help",
+			Description: "Print this message or the help of the given subcommand(s)",
+			Callback:    handlers.ServeHelpHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		{
+			Pattern:     "[global] shell [options]",
+			Description: "Start an interactive shell with a working directory",
+			Callback:    handlers.ShellHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.IntFlag{
+						Name:         "chunksize",
+						Patterns:     []string{"--chunksize"},
+						Description:  fmt.Sprintf("Set upload chunk size in bytes, default: %d", DefaultUploadChunkSize),
+						DefaultValue: DefaultUploadChunkSize,
+					},
+					cli.IntFlag{
+						Name:         "timeout",
+						Patterns:     []string{"--timeout"},
+						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
+						DefaultValue: DefaultTimeout,
+					},
+				),
+			},
+		},
 		{
 			Pattern:     "version",
 			Description: "Print application version",
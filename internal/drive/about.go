@@ -9,6 +9,7 @@ import (
 type AboutArgs struct {
 	Out         io.Writer
 	SizeInBytes bool
+	Output      OutputFormat
 }
 
 func (self *Drive) About(args AboutArgs) (err error) {
@@ -20,6 +21,17 @@ func (self *Drive) About(args AboutArgs) (err error) {
 	user := about.User
 	quota := about.StorageQuota
 
+	if args.Output != TableOutput {
+		return writeRecord(args.Out, args.Output, outputRecord{
+			{"displayName", user.DisplayName},
+			{"emailAddress", user.EmailAddress},
+			{"usage", quota.Usage},
+			{"free", quota.Limit - quota.Usage},
+			{"limit", quota.Limit},
+			{"maxUploadSize", about.MaxUploadSize},
+		})
+	}
+
 	fmt.Fprintf(args.Out, "User: %s, %s\n", user.DisplayName, user.EmailAddress)
 	fmt.Fprintf(args.Out, "Used: %s\n", formatSize(quota.Usage, args.SizeInBytes))
 	fmt.Fprintf(args.Out, "Free: %s\n", formatSize(quota.Limit-quota.Usage, args.SizeInBytes))
@@ -0,0 +1,161 @@
+package drive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const (
+	TarArchive   = "tar"
+	TarGzArchive = "tar.gz"
+	ZipArchive   = "zip"
+)
+
+var archiveMimes = map[string]string{
+	TarArchive:   "application/x-tar",
+	TarGzArchive: "application/gzip",
+	ZipArchive:   "application/zip",
+}
+
+// Writes directories and files to an archive as they are added, so that
+// the archive can be streamed without being staged on disk. The content of
+// a file is written to the returned writer before the next entry is created
+type archiveWriter interface {
+	writeDir(name string, modTime time.Time) error
+	createFile(name string, size int64, modTime time.Time) (io.Writer, error)
+	Close() error
+}
+
+func newArchiveWriter(w io.Writer, format string) (archiveWriter, error) {
+	switch format {
+	case TarArchive:
+		return &tarArchiveWriter{tw: tar.NewWriter(w)}, nil
+	case TarGzArchive:
+		gz := gzip.NewWriter(w)
+		return &tarArchiveWriter{tw: tar.NewWriter(gz), gz: gz}, nil
+	case ZipArchive:
+		return &zipArchiveWriter{zw: zip.NewWriter(w)}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown archive format '%s', use tar, tar.gz or zip", format)
+}
+
+type tarArchiveWriter struct {
+	tw *tar.Writer
+	gz *gzip.Writer
+}
+
+func (self *tarArchiveWriter) writeDir(name string, modTime time.Time) error {
+	return self.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     0755,
+		ModTime:  modTime,
+	})
+}
+
+// The size is written in front of the content, writing more or less
+// than the given size makes the archive fail
+func (self *tarArchiveWriter) createFile(name string, size int64, modTime time.Time) (io.Writer, error) {
+	err := self.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  modTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return self.tw, nil
+}
+
+func (self *tarArchiveWriter) Close() error {
+	if err := self.tw.Close(); err != nil {
+		return err
+	}
+
+	if self.gz != nil {
+		return self.gz.Close()
+	}
+
+	return nil
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (self *zipArchiveWriter) writeDir(name string, modTime time.Time) error {
+	_, err := self.zw.CreateHeader(&zip.FileHeader{
+		Name:     name + "/",
+		Modified: modTime,
+	})
+	return err
+}
+
+func (self *zipArchiveWriter) createFile(name string, size int64, modTime time.Time) (io.Writer, error) {
+	return self.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	})
+}
+
+func (self *zipArchiveWriter) Close() error {
+	return self.zw.Close()
+}
+
+// Archives the local files below a directory, paths in the archive start with rootName
+func archiveLocalFiles(w archiveWriter, rootName string, root os.FileInfo, files []*LocalFile) error {
+	if err := w.writeDir(rootName, root.ModTime()); err != nil {
+		return err
+	}
+
+	for _, lf := range files {
+		name := path.Join(rootName, filepath.ToSlash(lf.relPath))
+
+		if lf.info.IsDir() {
+			if err := w.writeDir(name, lf.info.ModTime()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := archiveLocalFile(w, name, lf); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+func archiveLocalFile(w archiveWriter, name string, lf *LocalFile) error {
+	f, err := os.Open(lf.absPath)
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %s", err)
+	}
+
+	// Close file on function exit
+	defer f.Close()
+
+	fw, err := w.createFile(name, lf.info.Size(), lf.info.ModTime())
+	if err != nil {
+		return err
+	}
+
+	// Only the size given in the header is read, in case the file is growing
+	if _, err := io.CopyN(fw, f, lf.info.Size()); err != nil {
+		return fmt.Errorf("Failed to archive '%s': %s", lf.relPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package drive
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Hashes content as it is transferred, so that it can be compared with the
+// checksums drive has for the file. Content is expected in order, parts that
+// are written again, e.g. when a transfer is retried, are only hashed once
+type checksumWriter struct {
+	md5    hash.Hash
+	sha1   hash.Hash
+	sha256 hash.Hash
+	offset int64
+}
+
+func newChecksumWriter() *checksumWriter {
+	return &checksumWriter{
+		md5:    md5.New(),
+		sha1:   sha1.New(),
+		sha256: sha256.New(),
+	}
+}
+
+func (self *checksumWriter) Write(p []byte) (int, error) {
+	return self.writeAt(p, self.offset)
+}
+
+// Hashes the part of p, which starts at offset in the content, that has not been hashed yet
+func (self *checksumWriter) writeAt(p []byte, offset int64) (int, error) {
+	if offset > self.offset {
+		return 0, fmt.Errorf("Content from offset %d to %d was not hashed", self.offset, offset)
+	}
+
+	skip := self.offset - offset
+	if skip < int64(len(p)) {
+		tail := p[skip:]
+		self.md5.Write(tail)
+		self.sha1.Write(tail)
+		self.sha256.Write(tail)
+		self.offset += int64(len(tail))
+	}
+
+	return len(p), nil
+}
+
+// Returns a writer for content starting at offset
+func (self *checksumWriter) at(offset int64) io.Writer {
+	return &offsetChecksumWriter{self, offset}
+}
+
+type offsetChecksumWriter struct {
+	checksums *checksumWriter
+	offset    int64
+}
+
+func (self *offsetChecksumWriter) Write(p []byte) (int, error) {
+	n, err := self.checksums.writeAt(p, self.offset)
+	self.offset += int64(n)
+	return n, err
+}
+
+// Hashes the first n bytes of a local file, i.e. the part of a
+// resumed transfer that was done by a previous run
+func (self *checksumWriter) hashFile(path string, n int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %s", err)
+	}
+
+	// Close file on function exit
+	defer f.Close()
+
+	if _, err := io.CopyN(self, f, n); err != nil {
+		return fmt.Errorf("Failed to hash file: %s", err)
+	}
+
+	return nil
+}
+
+// Compares the hashed content with the checksums drive has for the file,
+// checksums that drive does not provide are skipped
+func (self *checksumWriter) verify(md5Checksum, sha1Checksum, sha256Checksum string) error {
+	checks := []struct {
+		name   string
+		remote string
+		hash   hash.Hash
+	}{
+		{"sha256", sha256Checksum, self.sha256},
+		{"sha1", sha1Checksum, self.sha1},
+		{"md5", md5Checksum, self.md5},
+	}
+
+	for _, check := range checks {
+		if check.remote == "" {
+			continue
+		}
+
+		if local := hex.EncodeToString(check.hash.Sum(nil)); local != check.remote {
+			return fmt.Errorf("%s checksum mismatch, drive has %s but the transferred content has %s", check.name, check.remote, local)
+		}
+	}
+
+	return nil
+}
+
+// Checks the content of an upload against the checksums drive computed
+// for the new file. Nothing is checked when checksums is nil
+func verifyUpload(checksums *checksumWriter, f *drive.File) error {
+	if checksums == nil {
+		return nil
+	}
+
+	if err := checksums.verify(f.Md5Checksum, f.Sha1Checksum, f.Sha256Checksum); err != nil {
+		return fmt.Errorf("Failed to verify upload of %s: %s", f.Id, err)
+	}
+
+	return nil
+}
+
+// Hashes what is read from r, r is returned as it is when checksums is nil
+func checksumReader(r io.Reader, checksums *checksumWriter) io.Reader {
+	if checksums == nil {
+		return r
+	}
+	return io.TeeReader(r, checksums)
+}
@@ -0,0 +1,118 @@
+package drive
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestChecksumWriterWriteAt(t *testing.T) {
+	type write struct {
+		data   string
+		offset int64
+	}
+
+	tests := []struct {
+		name    string
+		writes  []write
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "in order",
+			writes: []write{{"hello ", 0}, {"world", 6}},
+			want:   "hello world",
+		},
+		{
+			name:   "empty write",
+			writes: []write{{"hello", 0}, {"", 5}},
+			want:   "hello",
+		},
+		{
+			name:   "retried part",
+			writes: []write{{"hello ", 0}, {"hello world", 0}},
+			want:   "hello world",
+		},
+		{
+			name:   "overlapping part",
+			writes: []write{{"hello wo", 0}, {"o world", 4}},
+			want:   "hello world",
+		},
+		{
+			name:   "part that was hashed already",
+			writes: []write{{"hello world", 0}, {"llo", 2}},
+			want:   "hello world",
+		},
+		{
+			name:    "gap",
+			writes:  []write{{"hello", 0}, {"world", 6}},
+			want:    "hello",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checksums := newChecksumWriter()
+
+			var err error
+			for _, w := range test.writes {
+				var n int
+				n, err = checksums.writeAt([]byte(w.data), w.offset)
+				if err != nil {
+					break
+				}
+				if n != len(w.data) {
+					t.Errorf("writeAt() = %d, want %d", n, len(w.data))
+				}
+			}
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("writeAt() error = %v, want error %v", err, test.wantErr)
+			}
+
+			if checksums.offset != int64(len(test.want)) {
+				t.Errorf("offset = %d, want %d", checksums.offset, len(test.want))
+			}
+
+			md5Sum := md5.Sum([]byte(test.want))
+			sha1Sum := sha1.Sum([]byte(test.want))
+			sha256Sum := sha256.Sum256([]byte(test.want))
+			if err := checksums.verify(hex.EncodeToString(md5Sum[:]), hex.EncodeToString(sha1Sum[:]), hex.EncodeToString(sha256Sum[:])); err != nil {
+				t.Errorf("verify() failed: %s", err)
+			}
+		})
+	}
+}
+
+func TestChecksumWriterVerify(t *testing.T) {
+	checksums := newChecksumWriter()
+	checksums.Write([]byte("hello"))
+
+	md5Sum := md5.Sum([]byte("hello"))
+	md5Hex := hex.EncodeToString(md5Sum[:])
+
+	tests := []struct {
+		name    string
+		md5     string
+		sha1    string
+		sha256  string
+		wantErr bool
+	}{
+		{name: "matching md5", md5: md5Hex},
+		{name: "no checksums"},
+		{name: "mismatching md5", md5: "00000000000000000000000000000000", wantErr: true},
+		{name: "mismatching sha256", md5: md5Hex, sha256: "00", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checksums.verify(test.md5, test.sha1, test.sha256)
+			if (err != nil) != test.wantErr {
+				t.Errorf("verify() error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -9,14 +9,51 @@ import (
 )
 
 type Drive struct {
-	service *drive.Service
+	service  *drive.Service
+	client   *http.Client
+	driveId  string
+	resolver *remoteResolver
+	key      *EncryptionKey
 }
 
-func New(client *http.Client) (*Drive, error) {
+// Creates a client for the users drive. With a driveId all file, sync and
+// changes operations work inside that shared drive instead of My Drive
+func New(client *http.Client, driveId string) (*Drive, error) {
 	service, err := drive.NewService(context.Background(), option.WithHTTPClient(client))
 	if err != nil {
 		return nil, err
 	}
 
-	return &Drive{service}, nil
+	return &Drive{service: service, client: client, driveId: driveId}, nil
+}
+
+// Sets the key used to encrypt uploads and decrypt encrypted files
+func (self *Drive) SetEncryptionKey(key *EncryptionKey) {
+	self.key = key
+}
+
+// Returns the folders new files are created in when no parent is given
+func (self *Drive) defaultParents(parents []string) []string {
+	if len(parents) == 0 && self.driveId != "" {
+		return []string{self.driveId}
+	}
+	return parents
+}
+
+// Starts a files list call that searches the selected shared drive, or My Drive if none is selected
+func (self *Drive) newFilesListCall() *drive.FilesListCall {
+	call := self.service.Files.List().SupportsAllDrives(true)
+	if self.driveId != "" {
+		call = call.Corpora("drive").DriveId(self.driveId).IncludeItemsFromAllDrives(true)
+	}
+	return call
+}
+
+// Starts a changes list call for the selected shared drive, or for the users drives if none is selected
+func (self *Drive) newChangesListCall(pageToken string) *drive.ChangesListCall {
+	call := self.service.Changes.List(pageToken).SupportsAllDrives(true)
+	if self.driveId != "" {
+		call = call.DriveId(self.driveId).IncludeItemsFromAllDrives(true)
+	}
+	return call
 }
@@ -13,6 +13,7 @@ type ListDrivesArgs struct {
 	Out            io.Writer
 	SkipHeader     bool
 	FieldSeparator string
+	Output         OutputFormat
 }
 
 func (self *Drive) ListDrives(args ListDrivesArgs) error {
@@ -21,6 +22,17 @@ func (self *Drive) ListDrives(args ListDrivesArgs) error {
 		return fmt.Errorf("Failed to list drives: %s", err)
 	}
 
+	if args.Output != TableOutput {
+		var records []outputRecord
+		for _, d := range drives {
+			records = append(records, outputRecord{
+				{"id", d.Id},
+				{"name", d.Name},
+			})
+		}
+		return writeRecords(args.Out, args.Output, args.SkipHeader, records)
+	}
+
 	if args.FieldSeparator == "\t" {
 		w := new(tabwriter.Writer)
 		w.Init(args.Out, 0, 0, 3, ' ', 0)
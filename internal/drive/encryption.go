@@ -0,0 +1,626 @@
+package drive
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imzza/gdrive/internal/utils"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// Content is encrypted with AES-256-GCM in chunks of 64 KiB. Each file is
+// encrypted with its own key, derived from the master key and a random salt
+// that is stored in front of the content. The nonce of a chunk holds the chunk
+// number and a flag that marks the last chunk, so that chunks can be neither
+// reordered nor cut off without the decryption failing
+const EncryptionScheme = "aes256gcm-stream-v1"
+
+// Environment variable holding the passphrase, so that it does not show up in the process list
+const PassphraseEnv = "GDRIVE_PASSPHRASE"
+
+const encryptionChunkSize = 64 * 1024
+const encryptionSaltSize = 32
+
+// Passphrases are stretched with a random salt that is created once per
+// installation, so that guesses can not be checked against a table made in
+// advance. The salt is stored with the files, so that they can be decrypted
+// with the passphrase elsewhere
+const passphraseIterations = 600000
+const passphraseSaltSize = 16
+
+// App properties describing an encrypted file
+const (
+	encryptionProperty     = "encryption"
+	encryptionKeyProperty  = "encryptionKey"
+	plainMd5Property       = "plainMd5"
+	plainSizeProperty      = "plainSize"
+	encryptedNameProperty  = "encryptedName"
+	passphraseSaltProperty = "encryptionSalt"
+)
+
+// The properties that only hold for encrypted content, they are
+// removed when an encrypted file is updated with plain content
+var contentEncryptionProperties = []string{encryptionProperty, encryptionKeyProperty, passphraseSaltProperty, plainMd5Property, plainSizeProperty}
+
+type EncryptionKey struct {
+	key         []byte
+	fingerprint string
+	// Salt of a key derived from a passphrase, empty for a key from a key file
+	salt string
+	// Files encrypted elsewhere need the passphrase derived with their salt
+	passphrase string
+	salted     map[string]*EncryptionKey
+	mutex      *sync.Mutex
+}
+
+// Loads the master key from a key file or derives it from a passphrase,
+// with the salt in saltFile which is created if it does not exist.
+// Nil is returned when neither a key file nor a passphrase is given
+func LoadEncryptionKey(keyFile, passphrase, saltFile string) (*EncryptionKey, error) {
+	if keyFile != "" && passphrase != "" {
+		return nil, fmt.Errorf("Use either a key file or a passphrase for encryption, not both")
+	}
+
+	if keyFile != "" {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read key file: %s", err)
+		}
+
+		if len(content) < 32 {
+			return nil, fmt.Errorf("Key file '%s' is too short, it should hold at least 32 random bytes", keyFile)
+		}
+
+		sum := sha256.Sum256(content)
+		return newEncryptionKey(sum[:], ""), nil
+	}
+
+	if passphrase == "" {
+		return nil, nil
+	}
+
+	salt, err := loadPassphraseSalt(saltFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := derivePassphraseKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	key.passphrase = passphrase
+	key.salted = map[string]*EncryptionKey{}
+	key.mutex = &sync.Mutex{}
+	return key, nil
+}
+
+func newEncryptionKey(key []byte, salt string) *EncryptionKey {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("gdrive key fingerprint"))
+
+	return &EncryptionKey{
+		key:         key,
+		fingerprint: hex.EncodeToString(mac.Sum(nil)[:8]),
+		salt:        salt,
+	}
+}
+
+func derivePassphraseKey(passphrase, salt string) (*EncryptionKey, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, []byte(salt), passphraseIterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive key from passphrase: %s", err)
+	}
+
+	return newEncryptionKey(key, salt), nil
+}
+
+// Reads the salt of this installation, a new random salt is saved if there is none
+func loadPassphraseSalt(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(content)) != "" {
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("Failed to read salt file: %s", err)
+	}
+
+	random := make([]byte, passphraseSaltSize)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("Failed to create salt: %s", err)
+	}
+	salt := hex.EncodeToString(random)
+
+	if err := mkdir(path); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(path, []byte(salt+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("Failed to save salt file: %s", err)
+	}
+
+	return salt, nil
+}
+
+// Returns the key that a file with the given salt was encrypted with. Only
+// a key from a passphrase differs by salt, the derived keys are kept as
+// deriving them is slow on purpose
+func (self *EncryptionKey) withSalt(salt string) (*EncryptionKey, error) {
+	if self.passphrase == "" || salt == "" || salt == self.salt {
+		return self, nil
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if key, ok := self.salted[salt]; ok {
+		return key, nil
+	}
+
+	key, err := derivePassphraseKey(self.passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	self.salted[salt] = key
+	return key, nil
+}
+
+// Stores the salt of a key from a passphrase with the file, which is needed to derive the key again
+func (self *EncryptionKey) setSaltProperty(dstFile *drive.File) {
+	if self.salt != "" {
+		dstFile.AppProperties[passphraseSaltProperty] = self.salt
+	}
+}
+
+func (self *EncryptionKey) deriveAead(salt []byte, info string) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, self.key, salt, info, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// Encrypts what is read from the source. The md5 and size of the
+// plain content are known when the source has been read to the end
+type encryptReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	buf     []byte
+	pending []byte
+	counter uint64
+	done    bool
+	md5     hash.Hash
+	size    int64
+}
+
+func (self *EncryptionKey) newEncryptReader(src io.Reader) (*encryptReader, error) {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("Failed to create salt: %s", err)
+	}
+
+	aead, err := self.deriveAead(salt, "gdrive file key")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create cipher: %s", err)
+	}
+
+	md5Hash := md5.New()
+
+	return &encryptReader{
+		src:     bufio.NewReaderSize(io.TeeReader(src, md5Hash), encryptionChunkSize),
+		aead:    aead,
+		buf:     make([]byte, encryptionChunkSize),
+		pending: salt,
+		md5:     md5Hash,
+	}, nil
+}
+
+func (self *encryptReader) Read(p []byte) (int, error) {
+	for len(self.pending) == 0 {
+		if self.done {
+			return 0, io.EOF
+		}
+
+		if err := self.sealChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, self.pending)
+	self.pending = self.pending[n:]
+	return n, nil
+}
+
+func (self *encryptReader) sealChunk() error {
+	n, err := io.ReadFull(self.src, self.buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	// A full chunk is the last one if nothing follows it
+	last := n < len(self.buf)
+	if !last {
+		if _, err := self.src.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	self.size += int64(n)
+	self.pending = self.aead.Seal(nil, chunkNonce(self.counter, last), self.buf[:n], nil)
+	self.counter++
+	self.done = last
+	return nil
+}
+
+func (self *encryptReader) plainMd5() string {
+	return hex.EncodeToString(self.md5.Sum(nil))
+}
+
+// Decrypts what is written to it and writes the plain content to the
+// underlying writer. Close fails if the content was cut off
+type decryptWriter struct {
+	w       io.Writer
+	key     *EncryptionKey
+	aead    cipher.AEAD
+	buf     []byte
+	counter uint64
+}
+
+func (self *EncryptionKey) newDecryptWriter(w io.Writer) *decryptWriter {
+	return &decryptWriter{w: w, key: self}
+}
+
+func (self *decryptWriter) Write(p []byte) (int, error) {
+	self.buf = append(self.buf, p...)
+
+	if self.aead == nil {
+		if len(self.buf) < encryptionSaltSize {
+			return len(p), nil
+		}
+
+		aead, err := self.key.deriveAead(self.buf[:encryptionSaltSize], "gdrive file key")
+		if err != nil {
+			return 0, fmt.Errorf("Failed to create cipher: %s", err)
+		}
+		self.aead = aead
+		self.buf = self.buf[encryptionSaltSize:]
+	}
+
+	// A chunk is only known not to be the last one when more content follows it
+	sealedSize := encryptionChunkSize + self.aead.Overhead()
+	for len(self.buf) > sealedSize {
+		if err := self.openChunk(self.buf[:sealedSize], false); err != nil {
+			return 0, err
+		}
+		self.buf = self.buf[sealedSize:]
+	}
+
+	// Keep the buffer from growing with the consumed chunks
+	self.buf = append([]byte(nil), self.buf...)
+	return len(p), nil
+}
+
+func (self *decryptWriter) openChunk(chunk []byte, last bool) error {
+	plain, err := self.aead.Open(nil, chunkNonce(self.counter, last), chunk, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to decrypt file, the content is damaged or was encrypted with another key")
+	}
+	self.counter++
+
+	_, err = self.w.Write(plain)
+	return err
+}
+
+func (self *decryptWriter) Close() error {
+	if self.aead == nil || len(self.buf) < self.aead.Overhead() {
+		return fmt.Errorf("Failed to decrypt file, the content is cut off")
+	}
+
+	return self.openChunk(self.buf, true)
+}
+
+// Names are encrypted deterministically, so that the same name always
+// gives the same encrypted name. The nonce is derived from the name and
+// checked after decryption
+func (self *EncryptionKey) encryptName(name string) (string, error) {
+	aead, err := self.deriveAead(nil, "gdrive name key")
+	if err != nil {
+		return "", err
+	}
+
+	nonce := self.nameNonce(name)
+	sealed := aead.Seal(nonce, nonce, []byte(name), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (self *EncryptionKey) decryptName(encrypted string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < 12 {
+		return "", fmt.Errorf("Invalid encrypted name '%s'", encrypted)
+	}
+
+	aead, err := self.deriveAead(nil, "gdrive name key")
+	if err != nil {
+		return "", err
+	}
+
+	name, err := aead.Open(nil, sealed[:12], sealed[12:], nil)
+	if err != nil || !bytes.Equal(self.nameNonce(string(name)), sealed[:12]) {
+		return "", fmt.Errorf("Failed to decrypt name '%s', it was encrypted with another key", encrypted)
+	}
+
+	return string(name), nil
+}
+
+func (self *EncryptionKey) nameNonce(name string) []byte {
+	mac := hmac.New(sha256.New, self.key)
+	mac.Write([]byte("gdrive name nonce"))
+	mac.Write([]byte(name))
+	return mac.Sum(nil)[:12]
+}
+
+func isEncrypted(f *drive.File) bool {
+	_, ok := f.AppProperties[encryptionProperty]
+	return ok
+}
+
+// Returns the size of the plain content of an encrypted file
+func plainSize(f *drive.File) int64 {
+	size, _ := strconv.ParseInt(f.AppProperties[plainSizeProperty], 10, 64)
+	return size
+}
+
+func hasEncryptedName(f *drive.File) bool {
+	_, ok := f.AppProperties[encryptedNameProperty]
+	return ok
+}
+
+// Prepares a file to be uploaded encrypted, the content must be read through
+// the returned reader. The name is encrypted too if requested
+func (self *Drive) encryptUpload(dstFile *drive.File, src io.Reader, encryptName bool) (*encryptReader, error) {
+	if self.key == nil {
+		return nil, fmt.Errorf("Encryption requires a key, use --key-file or set %s", PassphraseEnv)
+	}
+
+	if dstFile.AppProperties == nil {
+		dstFile.AppProperties = map[string]string{}
+	}
+
+	dstFile.AppProperties[encryptionProperty] = EncryptionScheme
+	dstFile.AppProperties[encryptionKeyProperty] = self.key.fingerprint
+	self.key.setSaltProperty(dstFile)
+
+	// The type of the content would tell something about it
+	dstFile.MimeType = "application/octet-stream"
+
+	if encryptName {
+		if err := self.setEncryptedName(dstFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return self.key.newEncryptReader(src)
+}
+
+// Replaces the name of a new file or directory with its encrypted name
+func (self *Drive) setEncryptedName(dstFile *drive.File) error {
+	if self.key == nil {
+		return fmt.Errorf("Encryption requires a key, use --key-file or set %s", PassphraseEnv)
+	}
+
+	name, err := self.key.encryptName(dstFile.Name)
+	if err != nil {
+		return fmt.Errorf("Failed to encrypt name: %s", err)
+	}
+
+	if dstFile.AppProperties == nil {
+		dstFile.AppProperties = map[string]string{}
+	}
+
+	dstFile.Name = name
+	dstFile.AppProperties[encryptedNameProperty] = "true"
+	self.key.setSaltProperty(dstFile)
+	return nil
+}
+
+// Marks an existing file as no longer encrypted when it is updated with plain content
+func clearContentEncryption(dstFile *drive.File) {
+	clearAppProperties(dstFile, contentEncryptionProperties...)
+}
+
+func clearAppProperties(dstFile *drive.File, properties ...string) {
+	for _, property := range properties {
+		dstFile.NullFields = append(dstFile.NullFields, "AppProperties."+property)
+	}
+
+	// Null entries are only sent along with the map itself
+	dstFile.ForceSendFields = append(dstFile.ForceSendFields, "AppProperties")
+}
+
+// Stores the md5 and size of the plain content once it has been
+// uploaded, sync compares them with the local files
+func (self *Drive) savePlainChecksum(fileId string, reader *encryptReader, fields []googleapi.Field) (*drive.File, error) {
+	dstFile := &drive.File{
+		AppProperties: map[string]string{
+			plainMd5Property:  reader.plainMd5(),
+			plainSizeProperty: strconv.FormatInt(reader.size, 10),
+		},
+	}
+
+	var f *drive.File
+	err := retryBackendErrors(func() error {
+		var err error
+		f, err = self.service.Files.Update(fileId, dstFile).SupportsAllDrives(true).Fields(fields...).Do()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+type encryptedUploadArgs struct {
+	src         io.Reader
+	size        int64
+	file        *drive.File
+	fileId      string
+	fields      []googleapi.Field
+	encryptName bool
+	chunkSize   int64
+	progress    io.Writer
+	timeout     time.Duration
+	checksums   *checksumWriter
+}
+
+// Uploads the source encrypted, the file is updated when a file id is given.
+// The size of the encrypted content differs from the source so the upload
+// can not be resumed from a stored session
+func (self *Drive) uploadEncrypted(args encryptedUploadArgs) (*drive.File, error) {
+	// Wrap source in progress reader, progress is shown for the plain content
+	progressReader := utils.GetProgressReader(args.src, args.progress, args.size)
+
+	encrypter, err := self.encryptUpload(args.file, progressReader, args.encryptName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrap reader in timeout reader
+	// The encrypted content is what drive computes its checksums for
+	reader, ctx := utils.GetTimeoutReaderContext(checksumReader(encrypter, args.checksums), args.timeout)
+
+	// Chunk size option
+	chunkSize := googleapi.ChunkSize(int(args.chunkSize))
+
+	// The checksum of the previous content must not outlive it in
+	// case storing the new one fails
+	if args.fileId != "" {
+		clearAppProperties(args.file, plainMd5Property, plainSizeProperty)
+	}
+
+	var f *drive.File
+	if args.fileId == "" {
+		f, err = self.service.Files.Create(args.file).SupportsAllDrives(true).Fields("id").Context(ctx).Media(reader, chunkSize).Do()
+	} else {
+		f, err = self.service.Files.Update(args.fileId, args.file).SupportsAllDrives(true).Fields("id").Context(ctx).Media(reader, chunkSize).Do()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := self.savePlainChecksum(f.Id, encrypter, args.fields)
+	if err != nil {
+		// A new file without the checksum would never match its source,
+		// so it is removed rather than left behind
+		if args.fileId == "" {
+			if delErr := self.deleteFile(f.Id, true); delErr != nil {
+				return nil, fmt.Errorf("Failed to save checksum of '%s': %s, failed to remove the uploaded file: %s", f.Id, err, delErr)
+			}
+		}
+		return nil, fmt.Errorf("Failed to save checksum of '%s': %s", f.Id, err)
+	}
+
+	return saved, nil
+}
+
+// Returns the key to decrypt an encrypted file with, after checking
+// that the file was encrypted with the loaded key
+func (self *Drive) decryptionKey(f *drive.File) (*EncryptionKey, error) {
+	if self.key == nil {
+		return nil, fmt.Errorf("'%s' is encrypted, use --key-file or set %s to decrypt it", f.Name, PassphraseEnv)
+	}
+
+	if scheme := f.AppProperties[encryptionProperty]; scheme != EncryptionScheme {
+		return nil, fmt.Errorf("'%s' is encrypted with the unknown scheme '%s'", f.Name, scheme)
+	}
+
+	key, err := self.key.withSalt(f.AppProperties[passphraseSaltProperty])
+	if err != nil {
+		return nil, err
+	}
+
+	if fingerprint := f.AppProperties[encryptionKeyProperty]; fingerprint != key.fingerprint {
+		return nil, fmt.Errorf("'%s' is encrypted with the key %s, the given key is %s", f.Name, fingerprint, key.fingerprint)
+	}
+
+	return key, nil
+}
+
+// Returns the plain name of a file with an encrypted name
+func (self *Drive) plainName(f *drive.File) (string, error) {
+	if !hasEncryptedName(f) {
+		return f.Name, nil
+	}
+
+	if self.key == nil {
+		return "", fmt.Errorf("The name of '%s' is encrypted, use --key-file or set %s to decrypt it", f.Name, PassphraseEnv)
+	}
+
+	key, err := self.key.withSalt(f.AppProperties[passphraseSaltProperty])
+	if err != nil {
+		return "", err
+	}
+
+	return key.decryptName(f.Name)
+}
+
+// Returns the files with their names decrypted, the given files are left as they are
+func (self *Drive) withPlainNames(files []*drive.File) ([]*drive.File, error) {
+	var plainFiles []*drive.File
+
+	for _, f := range files {
+		if hasEncryptedName(f) {
+			name, err := self.plainName(f)
+			if err != nil {
+				return nil, err
+			}
+
+			plain := *f
+			plain.Name = name
+			f = &plain
+		}
+
+		plainFiles = append(plainFiles, f)
+	}
+
+	return plainFiles, nil
+}
@@ -0,0 +1,199 @@
+package drive
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func testEncryptionKey(b byte) *EncryptionKey {
+	return newEncryptionKey(bytes.Repeat([]byte{b}, 32), "")
+}
+
+func encryptContent(t *testing.T, key *EncryptionKey, plain []byte) ([]byte, *encryptReader) {
+	t.Helper()
+
+	reader, err := key.newEncryptReader(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatalf("newEncryptReader() failed: %s", err)
+	}
+
+	encrypted, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %s", err)
+	}
+
+	return encrypted, reader
+}
+
+// Writes the content in small pieces, so that chunks are split across writes
+func decryptContent(key *EncryptionKey, encrypted []byte) ([]byte, error) {
+	var plain bytes.Buffer
+	w := key.newDecryptWriter(&plain)
+
+	for len(encrypted) > 0 {
+		n := 1000
+		if n > len(encrypted) {
+			n = len(encrypted)
+		}
+
+		if _, err := w.Write(encrypted[:n]); err != nil {
+			return nil, err
+		}
+		encrypted = encrypted[n:]
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return plain.Bytes(), nil
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	key := testEncryptionKey(1)
+
+	tests := []struct {
+		name   string
+		size   int
+		chunks int
+	}{
+		{name: "empty", size: 0, chunks: 1},
+		{name: "one byte", size: 1, chunks: 1},
+		{name: "one byte less than a chunk", size: encryptionChunkSize - 1, chunks: 1},
+		{name: "exactly one chunk", size: encryptionChunkSize, chunks: 1},
+		{name: "one byte more than a chunk", size: encryptionChunkSize + 1, chunks: 2},
+		{name: "exactly three chunks", size: 3 * encryptionChunkSize, chunks: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plain := make([]byte, test.size)
+			for i := range plain {
+				plain[i] = byte(i * 7)
+			}
+
+			encrypted, reader := encryptContent(t, key, plain)
+
+			wantSize := encryptionSaltSize + test.size + test.chunks*16
+			if len(encrypted) != wantSize {
+				t.Errorf("Encrypted size = %d, want %d", len(encrypted), wantSize)
+			}
+
+			sum := md5.Sum(plain)
+			if reader.plainMd5() != hex.EncodeToString(sum[:]) {
+				t.Errorf("plainMd5() = %s, want %s", reader.plainMd5(), hex.EncodeToString(sum[:]))
+			}
+
+			if reader.size != int64(test.size) {
+				t.Errorf("Plain size = %d, want %d", reader.size, test.size)
+			}
+
+			decrypted, err := decryptContent(key, encrypted)
+			if err != nil {
+				t.Fatalf("Failed to decrypt: %s", err)
+			}
+
+			if !bytes.Equal(decrypted, plain) {
+				t.Errorf("Decrypted content differs from the plain content")
+			}
+		})
+	}
+}
+
+func TestDecryptDamagedContent(t *testing.T) {
+	key := testEncryptionKey(1)
+
+	plain := bytes.Repeat([]byte("x"), 2*encryptionChunkSize)
+	encrypted, _ := encryptContent(t, key, plain)
+	sealedSize := encryptionChunkSize + 16
+
+	flipped := append([]byte(nil), encrypted...)
+	flipped[encryptionSaltSize+10] ^= 1
+
+	tests := []struct {
+		name      string
+		key       *EncryptionKey
+		encrypted []byte
+	}{
+		{name: "another key", key: testEncryptionKey(2), encrypted: encrypted},
+		{name: "flipped bit", key: key, encrypted: flipped},
+		{name: "cut off in a chunk", key: key, encrypted: encrypted[:len(encrypted)-1]},
+		{name: "cut off after a chunk", key: key, encrypted: encrypted[:encryptionSaltSize+sealedSize]},
+		{name: "salt only", key: key, encrypted: encrypted[:encryptionSaltSize]},
+		{name: "nothing", key: key, encrypted: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := decryptContent(test.key, test.encrypted); err == nil {
+				t.Errorf("Decrypting succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestEncryptName(t *testing.T) {
+	key := testEncryptionKey(1)
+
+	for _, name := range []string{"a", "report.pdf", "été 日本.txt"} {
+		encrypted, err := key.encryptName(name)
+		if err != nil {
+			t.Fatalf("encryptName(%q) failed: %s", name, err)
+		}
+
+		again, _ := key.encryptName(name)
+		if again != encrypted {
+			t.Errorf("encryptName(%q) is not deterministic: %s and %s", name, encrypted, again)
+		}
+
+		decrypted, err := key.decryptName(encrypted)
+		if err != nil || decrypted != name {
+			t.Errorf("decryptName(%q) = %q, %v, want %q", encrypted, decrypted, err, name)
+		}
+
+		if _, err := testEncryptionKey(2).decryptName(encrypted); err == nil {
+			t.Errorf("decryptName(%q) with another key succeeded, want an error", encrypted)
+		}
+	}
+}
+
+func TestPassphraseSalt(t *testing.T) {
+	dir := t.TempDir()
+
+	key, err := LoadEncryptionKey("", "secret", filepath.Join(dir, "a", "salt"))
+	if err != nil {
+		t.Fatalf("LoadEncryptionKey() failed: %s", err)
+	}
+
+	again, err := LoadEncryptionKey("", "secret", filepath.Join(dir, "a", "salt"))
+	if err != nil {
+		t.Fatalf("LoadEncryptionKey() failed: %s", err)
+	}
+
+	if again.fingerprint != key.fingerprint {
+		t.Errorf("The stored salt gave another key, %s and %s", key.fingerprint, again.fingerprint)
+	}
+
+	other, err := LoadEncryptionKey("", "secret", filepath.Join(dir, "b", "salt"))
+	if err != nil {
+		t.Fatalf("LoadEncryptionKey() failed: %s", err)
+	}
+
+	if other.fingerprint == key.fingerprint {
+		t.Errorf("Another salt gave the same key %s", key.fingerprint)
+	}
+
+	// Content encrypted with the first salt can be decrypted with the passphrase elsewhere
+	salted, err := other.withSalt(key.salt)
+	if err != nil {
+		t.Fatalf("withSalt() failed: %s", err)
+	}
+
+	if salted.fingerprint != key.fingerprint {
+		t.Errorf("withSalt() gave the key %s, want %s", salted.fingerprint, key.fingerprint)
+	}
+}
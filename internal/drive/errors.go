@@ -30,6 +30,26 @@ func isRateLimitError(err error) bool {
 	return ok && ae.Code == 403
 }
 
+// Calls fn again with an increasing delay while it fails with a backend or rate limit error
+func retryBackendErrors(fn func() error) error {
+	for try := 0; ; try++ {
+		err := fn()
+		if !isBackendOrRateLimitError(err) || try >= MaxErrorRetries {
+			return err
+		}
+		exponentialBackoffSleep(try)
+	}
+}
+
+// Api errors other than backend and rate limit errors will not go away by retrying,
+// other errors are typically caused by an interrupted connection
+func isRetryableTransferError(err error) bool {
+	if _, ok := err.(*googleapi.Error); ok {
+		return isBackendOrRateLimitError(err)
+	}
+	return true
+}
+
 func isTimeoutError(err error) bool {
 	return err == context.Canceled
 }
@@ -3,10 +3,17 @@ package drive
 import (
 	"fmt"
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"io"
 	"text/tabwriter"
+	"time"
 )
 
+const changesFields = "newStartPageToken,nextPageToken,changes(fileId,removed,time,file(id,name,md5Checksum,mimeType,createdTime,modifiedTime))"
+
+// Any field may be used in a format template
+const changesFormatFields = "newStartPageToken,nextPageToken,changes(*)"
+
 type ListChangesArgs struct {
 	Out        io.Writer
 	PageToken  string
@@ -14,6 +21,12 @@ type ListChangesArgs struct {
 	Now        bool
 	NameWidth  int64
 	SkipHeader bool
+	Follow     bool
+	Interval   time.Duration
+	StateFile  string
+	Command    string
+	Output     OutputFormat
+	Format     string
 }
 
 func (self *Drive) ListChanges(args ListChangesArgs) error {
@@ -27,9 +40,32 @@ func (self *Drive) ListChanges(args ListChangesArgs) error {
 		return nil
 	}
 
-	changeList, err := self.service.Changes.List(args.PageToken).PageSize(args.MaxChanges).RestrictToMyDrive(true).Fields("newStartPageToken", "nextPageToken", "changes(fileId,removed,time,file(id,name,md5Checksum,mimeType,createdTime,modifiedTime))").Do()
+	tmpl, err := self.newFormatTemplate(args.Format)
 	if err != nil {
-		return fmt.Errorf("Failed listing changes: %s", err)
+		return err
+	}
+
+	if args.Follow {
+		return self.followChanges(args, tmpl)
+	}
+
+	pageToken := args.PageToken
+	if pageToken == "" {
+		pageToken = "1"
+	}
+
+	changeList, err := self.listChangesPage(pageToken, args.MaxChanges, tmpl != nil, 0)
+	if err != nil {
+		return err
+	}
+
+	if tmpl != nil {
+		for _, c := range changeList.Changes {
+			if err := printFormatted(args.Out, tmpl, c); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
 	PrintChanges(PrintChangesArgs{
@@ -37,13 +73,43 @@ func (self *Drive) ListChanges(args ListChangesArgs) error {
 		ChangeList: changeList,
 		NameWidth:  int(args.NameWidth),
 		SkipHeader: args.SkipHeader,
+		Output:     args.Output,
 	})
 
 	return nil
 }
 
+func (self *Drive) listChangesPage(pageToken string, maxChanges int64, allFields bool, try int) (*drive.ChangeList, error) {
+	fields := googleapi.Field(changesFields)
+	if allFields {
+		fields = googleapi.Field(changesFormatFields)
+	}
+
+	call := self.newChangesListCall(pageToken).PageSize(maxChanges).Fields(fields)
+	if self.driveId == "" {
+		call = call.RestrictToMyDrive(true)
+	}
+
+	changeList, err := call.Do()
+	if err != nil {
+		if isBackendOrRateLimitError(err) && try < MaxErrorRetries {
+			exponentialBackoffSleep(try)
+			try++
+			return self.listChangesPage(pageToken, maxChanges, allFields, try)
+		}
+		return nil, fmt.Errorf("Failed listing changes: %s", err)
+	}
+
+	return changeList, nil
+}
+
 func (self *Drive) GetChangesStartPageToken() (string, error) {
-	res, err := self.service.Changes.GetStartPageToken().Do()
+	call := self.service.Changes.GetStartPageToken().SupportsAllDrives(true)
+	if self.driveId != "" {
+		call = call.DriveId(self.driveId)
+	}
+
+	res, err := call.Do()
 	if err != nil {
 		return "", fmt.Errorf("Failed getting start page token: %s", err)
 	}
@@ -56,9 +122,31 @@ type PrintChangesArgs struct {
 	ChangeList *drive.ChangeList
 	NameWidth  int
 	SkipHeader bool
+	Output     OutputFormat
 }
 
 func PrintChanges(args PrintChangesArgs) {
+	if args.Output != TableOutput {
+		records := []outputRecord{}
+		for _, c := range args.ChangeList.Changes {
+			records = append(records, changeRecord(c))
+		}
+
+		// The page token is needed to continue listing, json has room for it next to the changes
+		if args.Output == JsonOutput {
+			pageToken, hasMore := nextChangesPageToken(args.ChangeList)
+			writeRecord(args.Out, args.Output, outputRecord{
+				{"changes", records},
+				{"pageToken", pageToken},
+				{"more", hasMore},
+			})
+			return
+		}
+
+		writeRecords(args.Out, args.Output, args.SkipHeader, records)
+		return
+	}
+
 	w := new(tabwriter.Writer)
 	w.Init(args.Out, 0, 0, 3, ' ', 0)
 
@@ -67,20 +155,10 @@ func PrintChanges(args PrintChangesArgs) {
 	}
 
 	for _, c := range args.ChangeList.Changes {
-		var name string
-		var action string
-
-		if c.Removed {
-			action = "remove"
-		} else {
-			name = c.File.Name
-			action = "update"
-		}
-
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
 			c.FileId,
-			truncateString(name, args.NameWidth),
-			action,
+			truncateString(changeFileName(c), args.NameWidth),
+			changeAction(c),
 			formatDatetime(c.Time),
 		)
 	}
@@ -101,3 +179,32 @@ func nextChangesPageToken(cl *drive.ChangeList) (string, bool) {
 
 	return cl.NewStartPageToken, false
 }
+
+func changeRecord(c *drive.Change) outputRecord {
+	var mimeType string
+	if c.File != nil {
+		mimeType = c.File.MimeType
+	}
+
+	return outputRecord{
+		{"fileId", c.FileId},
+		{"name", changeFileName(c)},
+		{"action", changeAction(c)},
+		{"mimeType", mimeType},
+		{"time", c.Time},
+	}
+}
+
+func changeAction(c *drive.Change) string {
+	if c.Removed {
+		return "remove"
+	}
+	return "update"
+}
+
+func changeFileName(c *drive.Change) string {
+	if c.File == nil {
+		return ""
+	}
+	return c.File.Name
+}
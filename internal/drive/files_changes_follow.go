@@ -0,0 +1,154 @@
+package drive
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/imzza/gdrive/internal/utils"
+	"google.golang.org/api/drive/v3"
+)
+
+// Position in the changes feed, saved after every handled page
+type changesState struct {
+	PageToken string `json:"pageToken"`
+}
+
+// Polls for changes and prints them as they arrive. Listing starts at the given
+// page token, the token saved in the state file or the current position, in that order
+func (self *Drive) followChanges(args ListChangesArgs, tmpl *template.Template) error {
+	pageToken := args.PageToken
+
+	if pageToken == "" && args.StateFile != "" {
+		state, err := loadChangesState(args.StateFile)
+		if err == nil {
+			pageToken = state.PageToken
+		}
+	}
+
+	if pageToken == "" {
+		var err error
+		pageToken, err = self.GetChangesStartPageToken()
+		if err != nil {
+			return err
+		}
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(args.Out, 0, 0, 3, ' ', 0)
+
+	// A json list is never finished, changes are written as a stream of objects instead
+	format := args.Output
+	if format == JsonOutput {
+		format = NdjsonOutput
+	}
+	records := newRecordWriter(args.Out, format, args.SkipHeader)
+
+	if !args.SkipHeader && format == TableOutput && tmpl == nil {
+		fmt.Fprintln(w, "Id\tName\tAction\tTime")
+		w.Flush()
+	}
+
+	for {
+		changeList, err := self.listChangesPage(pageToken, args.MaxChanges, tmpl != nil, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range changeList.Changes {
+			if tmpl != nil {
+				if err := printFormatted(args.Out, tmpl, c); err != nil {
+					return err
+				}
+			} else if format == TableOutput {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+					c.FileId,
+					truncateString(changeFileName(c), int(args.NameWidth)),
+					changeAction(c),
+					formatDatetime(c.Time),
+				)
+				w.Flush()
+			} else {
+				records.write(changeRecord(c))
+			}
+
+			if args.Command != "" {
+				runChangeCommand(args, c)
+			}
+		}
+
+		nextToken, hasMore := nextChangesPageToken(changeList)
+
+		// The token is saved after the hooks have run, so an interrupted run repeats the page rather than missing it
+		if args.StateFile != "" && nextToken != pageToken {
+			err = saveChangesState(args.StateFile, changesState{PageToken: nextToken})
+			if err != nil {
+				return fmt.Errorf("Failed to save changes state: %s", err)
+			}
+		}
+
+		pageToken = nextToken
+
+		if !hasMore {
+			time.Sleep(args.Interval)
+		}
+	}
+}
+
+// Runs the user command for a change with details about the change in the environment
+func runChangeCommand(args ListChangesArgs, c *drive.Change) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", args.Command)
+	} else {
+		cmd = exec.Command("sh", "-c", args.Command)
+	}
+
+	var mimeType string
+	if c.File != nil {
+		mimeType = c.File.MimeType
+	}
+
+	cmd.Env = append(os.Environ(),
+		"GDRIVE_FILE_ID="+c.FileId,
+		"GDRIVE_FILE_NAME="+changeFileName(c),
+		"GDRIVE_ACTION="+changeAction(c),
+		"GDRIVE_MIME_TYPE="+mimeType,
+		"GDRIVE_CHANGE_TIME="+c.Time,
+	)
+	cmd.Stdout = args.Out
+	cmd.Stderr = os.Stderr
+
+	// A failing command should not stop us from following changes
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Command failed for %s: %s\n", c.FileId, err)
+	}
+}
+
+func loadChangesState(path string) (*changesState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	state := &changesState{}
+	if err := json.NewDecoder(f).Decode(state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func saveChangesState(path string, state changesState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return utils.WriteJSON(path, state)
+}
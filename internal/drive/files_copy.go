@@ -3,27 +3,38 @@ package drive
 import (
 	"fmt"
 	"io"
+	"path/filepath"
+	"text/tabwriter"
 
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 )
 
 type CopyArgs struct {
-	Out      io.Writer
-	Id       string
-	FolderId string
+	Out        io.Writer
+	Id         string
+	FolderId   string
+	Recursive  bool
+	Preserve   bool
+	SkipHeader bool
+	Output     OutputFormat
 }
 
 func (self *Drive) Copy(args CopyArgs) error {
-	f, err := self.service.Files.Get(args.Id).Fields("name,mimeType").Do()
+	if err := self.resolvePaths(&args.Id, &args.FolderId); err != nil {
+		return err
+	}
+
+	f, err := self.service.Files.Get(args.Id).SupportsAllDrives(true).Fields("id,name,mimeType,description,properties").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get file: %s", err)
 	}
 
-	if isDir(f) {
-		return fmt.Errorf("Copy directories is not supported")
+	if isDir(f) && !args.Recursive {
+		return fmt.Errorf("'%s' is a directory, use the 'recursive' flag to copy directories", f.Name)
 	}
 
-	dest, err := self.service.Files.Get(args.FolderId).Fields("name,mimeType").Do()
+	dest, err := self.service.Files.Get(args.FolderId).SupportsAllDrives(true).Fields("name,mimeType").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get destination folder: %s", err)
 	}
@@ -32,17 +43,166 @@ func (self *Drive) Copy(args CopyArgs) error {
 		return fmt.Errorf("Can only copy to a directory")
 	}
 
+	if isDir(f) {
+		return self.copyRecursive(f, dest, args)
+	}
+
 	fmt.Fprintf(args.Out, "Copying '%s' to '%s'\n", f.Name, dest.Name)
 
-	copyFile := &drive.File{
-		Parents: []string{args.FolderId},
+	newFile, err := self.copyFile(f, args.FolderId, args.Preserve, 0)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Copied '%s' (id: %s)\n", newFile.Name, newFile.Id)
+	return nil
+}
+
+// Old and new id of a copied file
+type copiedFile struct {
+	oldId string
+	newId string
+	path  string
+}
+
+type copyTree struct {
+	args   CopyArgs
+	copies []*copiedFile
+	// Ids of the created directories, a directory copied into itself must not be copied again
+	created map[string]bool
+}
+
+func (self *Drive) copyRecursive(dir *drive.File, dest *drive.File, args CopyArgs) error {
+	tree := &copyTree{
+		args:    args,
+		created: map[string]bool{},
+	}
+
+	// Progress is only printed for tables, so that structured output can be parsed
+	if args.Output == TableOutput {
+		fmt.Fprintf(args.Out, "Copying '%s' to '%s'\n", dir.Name, dest.Name)
 	}
 
-	newFile, err := self.service.Files.Copy(args.Id, copyFile).Fields("id,name").SupportsAllDrives(true).Do()
+	err := self.copyDirectory(dir, args.FolderId, dir.Name, tree)
+
+	// The files copied so far are printed also when the copy failed half way
+	if printErr := printCopiedFiles(args, tree.copies); printErr != nil {
+		return printErr
+	}
+
+	return err
+}
+
+func (self *Drive) copyDirectory(dir *drive.File, parentId string, path string, tree *copyTree) error {
+	dstDir := &drive.File{
+		Name:     dir.Name,
+		MimeType: DirectoryMimeType,
+		Parents:  []string{parentId},
+	}
+
+	if tree.args.Preserve {
+		dstDir.Description = dir.Description
+		dstDir.Properties = dir.Properties
+	}
+
+	newDir, err := self.service.Files.Create(dstDir).SupportsAllDrives(true).Fields("id", "name").Do()
 	if err != nil {
-		return fmt.Errorf("Failed to move file: %s", err)
+		return fmt.Errorf("Failed to create directory: %s", err)
+	}
+
+	tree.created[newDir.Id] = true
+	tree.copies = append(tree.copies, &copiedFile{dir.Id, newDir.Id, path})
+
+	listArgs := listAllFilesArgs{
+		query:     fmt.Sprintf("'%s' in parents and trashed = false", dir.Id),
+		fields:    []googleapi.Field{"nextPageToken", "files(id,name,mimeType,description,properties)"},
+		sortOrder: "folder,name",
+	}
+	files, err := self.listAllFiles(listArgs)
+	if err != nil {
+		return fmt.Errorf("Failed listing files: %s", err)
+	}
+
+	for _, f := range files {
+		if tree.created[f.Id] {
+			continue
+		}
+
+		childPath := filepath.Join(path, f.Name)
+
+		if isDir(f) {
+			err = self.copyDirectory(f, newDir.Id, childPath, tree)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		newFile, err := self.copyFile(f, newDir.Id, tree.args.Preserve, 0)
+		if err != nil {
+			return err
+		}
+
+		tree.copies = append(tree.copies, &copiedFile{f.Id, newFile.Id, childPath})
+
+		if tree.args.Output == TableOutput {
+			fmt.Fprintf(tree.args.Out, "Copied %s\n", childPath)
+		}
 	}
 
-	fmt.Fprintf(args.Out, "Copied '%s' (id: %s)\n", newFile.Name, newFile.Id)
 	return nil
 }
+
+// Makes a server side copy of the file in the given directory
+func (self *Drive) copyFile(f *drive.File, parentId string, preserve bool, try int) (*drive.File, error) {
+	dstFile := &drive.File{
+		Name:    f.Name,
+		Parents: []string{parentId},
+	}
+
+	if preserve {
+		dstFile.Description = f.Description
+		dstFile.Properties = f.Properties
+	}
+
+	newFile, err := self.service.Files.Copy(f.Id, dstFile).Fields("id,name").SupportsAllDrives(true).Do()
+	if err != nil {
+		if isBackendOrRateLimitError(err) && try < MaxErrorRetries {
+			exponentialBackoffSleep(try)
+			try++
+			return self.copyFile(f, parentId, preserve, try)
+		}
+		return nil, fmt.Errorf("Failed to copy file: %s", err)
+	}
+
+	return newFile, nil
+}
+
+// Prints which new id each of the copied files got
+func printCopiedFiles(args CopyArgs, copies []*copiedFile) error {
+	if args.Output != TableOutput {
+		var records []outputRecord
+		for _, c := range copies {
+			records = append(records, outputRecord{
+				{"oldId", c.oldId},
+				{"newId", c.newId},
+				{"path", c.path},
+			})
+		}
+		return writeRecords(args.Out, args.Output, args.SkipHeader, records)
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(args.Out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "")
+	if !args.SkipHeader {
+		fmt.Fprintln(w, "Old id\tNew id\tPath")
+	}
+
+	for _, c := range copies {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.oldId, c.newId, c.path)
+	}
+
+	return w.Flush()
+}
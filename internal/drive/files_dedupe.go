@@ -0,0 +1,319 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	DedupeKeepOldest = "oldest"
+	DedupeKeepNewest = "newest"
+)
+
+const (
+	contentMatch = "content"
+	nameMatch    = "name"
+)
+
+var dedupeFields = []googleapi.Field{"id", "name", "mimeType", "size", "md5Checksum", "createdTime", "parents", "appProperties"}
+
+type DedupeArgs struct {
+	Out   io.Writer
+	Query FileQuery
+	// Which file of a group is kept: oldest, newest or the one at or below a path
+	Keep        string
+	Trash       bool
+	SkipHeader  bool
+	SizeInBytes bool
+	Output      OutputFormat
+}
+
+type duplicateGroup struct {
+	match string
+	// Oldest first
+	files []*duplicateFile
+	// The file that is kept when the others are trashed, nil if there is none
+	kept *duplicateFile
+}
+
+type duplicateFile struct {
+	file *drive.File
+	path string
+}
+
+// Finds files with the same content, and files with the same name in the
+// same directory, which sync refuses to handle. Only files with the same
+// content are trashed, all but one of each group according to the keep policy
+func (self *Drive) Dedupe(args DedupeArgs) error {
+	if args.Query.Trashed && args.Trash {
+		return fmt.Errorf("Files in the trash can not be trashed, --trash can not be used with --trashed")
+	}
+
+	keep := args.Keep
+	if keep == "" {
+		keep = DedupeKeepOldest
+	} else if keep != DedupeKeepOldest && keep != DedupeKeepNewest {
+		keep = strings.Trim(path.Clean(keep), "/")
+	}
+
+	query, err := self.buildQuery(args.Query)
+	if err != nil {
+		return err
+	}
+
+	listArgs := listAllFilesArgs{
+		query:   query,
+		fields:  []googleapi.Field{"nextPageToken", googleapi.Field(fmt.Sprintf("files(%s)", googleapi.CombineFields(dedupeFields)))},
+		minSize: args.Query.LargerThan,
+	}
+	files, err := self.listAllFiles(listArgs)
+	if err != nil {
+		return fmt.Errorf("Failed to list files: %s", err)
+	}
+
+	groups := findDuplicates(files)
+
+	pathfinder := self.newPathfinder()
+
+	for _, g := range groups {
+		for _, df := range g.files {
+			df.path, err = pathfinder.absPath(df.file)
+			if err != nil {
+				return err
+			}
+		}
+
+		if g.match == contentMatch {
+			g.chooseKept(keep)
+		}
+	}
+
+	if err := printDuplicates(args, groups); err != nil {
+		return err
+	}
+
+	if !args.Trash {
+		return nil
+	}
+
+	for _, g := range groups {
+		for _, df := range g.files {
+			if g.action(df) != "trash" {
+				continue
+			}
+
+			if _, err := self.trashFile(df.file.Id); err != nil {
+				return err
+			}
+
+			if args.Output == TableOutput {
+				fmt.Fprintf(args.Out, "Trashed '%s'\n", df.path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Groups the files by content and by name and parent. Content groups come
+// first with the ones that waste the most space at the top
+func findDuplicates(files []*drive.File) []*duplicateGroup {
+	byContent := map[string]*duplicateGroup{}
+	byName := map[string]*duplicateGroup{}
+
+	var contentGroups []*duplicateGroup
+	var nameGroups []*duplicateGroup
+
+	for _, f := range files {
+		if key := contentKey(f); key != "" {
+			g, ok := byContent[key]
+			if !ok {
+				g = &duplicateGroup{match: contentMatch}
+				byContent[key] = g
+				contentGroups = append(contentGroups, g)
+			}
+			g.files = append(g.files, &duplicateFile{file: f})
+		}
+
+		if len(f.Parents) > 0 {
+			key := f.Parents[0] + "/" + f.Name
+			g, ok := byName[key]
+			if !ok {
+				g = &duplicateGroup{match: nameMatch}
+				byName[key] = g
+				nameGroups = append(nameGroups, g)
+			}
+			g.files = append(g.files, &duplicateFile{file: f})
+		}
+	}
+
+	var groups []*duplicateGroup
+
+	for _, g := range contentGroups {
+		if len(g.files) > 1 {
+			groups = append(groups, g)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].wasted() > groups[j].wasted()
+	})
+
+	for _, g := range nameGroups {
+		// Siblings with the same content are in a content group already
+		if len(g.files) > 1 && !g.sameContent() {
+			groups = append(groups, g)
+		}
+	}
+
+	for _, g := range groups {
+		sort.SliceStable(g.files, func(i, j int) bool {
+			return g.files[i].file.CreatedTime < g.files[j].file.CreatedTime
+		})
+	}
+
+	return groups
+}
+
+// Returns the checksum and size of the content, or an empty string for
+// files without content. Empty files are often markers that belong where
+// they are and are not duplicates of each other. The plain content of
+// encrypted files is compared, as every upload of the same content is
+// encrypted differently
+func contentKey(f *drive.File) string {
+	md5, size := f.Md5Checksum, f.Size
+	if isEncrypted(f) {
+		md5, size = f.AppProperties[plainMd5Property], plainSize(f)
+	}
+
+	if md5 == "" || size == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s:%d", md5, size)
+}
+
+func (self *duplicateGroup) sameContent() bool {
+	key := contentKey(self.files[0].file)
+	if key == "" {
+		return false
+	}
+
+	for _, df := range self.files[1:] {
+		if contentKey(df.file) != key {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Space used by all but one of the files
+func (self *duplicateGroup) wasted() int64 {
+	return self.files[0].file.Size * int64(len(self.files)-1)
+}
+
+// Picks the file to keep, files are sorted oldest first. When a path is
+// given the oldest file at or below that path is kept, nothing is kept
+// if there is no such file
+func (self *duplicateGroup) chooseKept(keep string) {
+	switch keep {
+	case DedupeKeepOldest:
+		self.kept = self.files[0]
+	case DedupeKeepNewest:
+		self.kept = self.files[len(self.files)-1]
+	default:
+		for _, df := range self.files {
+			if df.path == keep || strings.HasPrefix(df.path, keep+"/") {
+				self.kept = df
+				return
+			}
+		}
+	}
+}
+
+// Returns keep or trash for the files of content groups where a file to
+// keep was found, files of other groups are left alone
+func (self *duplicateGroup) action(df *duplicateFile) string {
+	if self.kept == nil {
+		return ""
+	}
+
+	if df == self.kept {
+		return "keep"
+	}
+	return "trash"
+}
+
+func printDuplicates(args DedupeArgs, groups []*duplicateGroup) error {
+	if args.Output != TableOutput {
+		records := []outputRecord{}
+		for i, g := range groups {
+			for _, df := range g.files {
+				records = append(records, outputRecord{
+					{"group", int64(i + 1)},
+					{"match", g.match},
+					{"id", df.file.Id},
+					{"path", df.path},
+					{"size", df.file.Size},
+					{"md5Checksum", df.file.Md5Checksum},
+					{"createdTime", df.file.CreatedTime},
+					{"action", g.action(df)},
+				})
+			}
+		}
+		return writeRecords(args.Out, args.Output, args.SkipHeader, records)
+	}
+
+	if len(groups) == 0 {
+		fmt.Fprintln(args.Out, "No duplicates found")
+		return nil
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(args.Out, 0, 0, 3, ' ', 0)
+
+	if !args.SkipHeader {
+		fmt.Fprintln(w, "Group\tMatch\tId\tPath\tSize\tCreated\tAction")
+	}
+
+	var count int
+	var freed int64
+
+	for i, g := range groups {
+		for _, df := range g.files {
+			action := g.action(df)
+			if action == "trash" {
+				count++
+				freed += df.file.Size
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				i+1,
+				g.match,
+				df.file.Id,
+				df.path,
+				formatSize(df.file.Size, args.SizeInBytes),
+				formatDatetime(df.file.CreatedTime),
+				action,
+			)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if count > 0 && !args.Trash {
+		fmt.Fprintf(args.Out, "\nUse --trash to trash the %d files marked trash, which frees %s\n", count, formatSize(freed, args.SizeInBytes))
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package drive
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	file := func(id, parent, name, md5 string, size int64, created string) *drive.File {
+		return &drive.File{Id: id, Parents: []string{parent}, Name: name, Md5Checksum: md5, Size: size, CreatedTime: created}
+	}
+
+	encrypted := func(id, parent, name, md5, plainMd5, plainSize string) *drive.File {
+		f := file(id, parent, name, md5, 100, "2024-01-01T00:00:00Z")
+		f.AppProperties = map[string]string{
+			encryptionProperty: EncryptionScheme,
+			plainMd5Property:   plainMd5,
+			plainSizeProperty:  plainSize,
+		}
+		return f
+	}
+
+	type group struct {
+		match string
+		ids   []string
+	}
+
+	tests := []struct {
+		name  string
+		files []*drive.File
+		want  []group
+	}{
+		{
+			name: "no duplicates",
+			files: []*drive.File{
+				file("a", "p", "a.txt", "md5a", 5, "2024-01-01T00:00:00Z"),
+				file("b", "p", "b.txt", "md5b", 5, "2024-01-01T00:00:00Z"),
+			},
+		},
+		{
+			name: "same content oldest first",
+			files: []*drive.File{
+				file("new", "p", "b.txt", "md5", 5, "2024-01-02T00:00:00Z"),
+				file("old", "q", "a.txt", "md5", 5, "2024-01-01T00:00:00Z"),
+			},
+			want: []group{{contentMatch, []string{"old", "new"}}},
+		},
+		{
+			name: "most wasted space first",
+			files: []*drive.File{
+				file("s1", "p", "s1", "small", 5, "2024-01-01T00:00:00Z"),
+				file("s2", "p", "s2", "small", 5, "2024-01-02T00:00:00Z"),
+				file("b1", "p", "b1", "big", 50, "2024-01-01T00:00:00Z"),
+				file("b2", "p", "b2", "big", 50, "2024-01-02T00:00:00Z"),
+			},
+			want: []group{
+				{contentMatch, []string{"b1", "b2"}},
+				{contentMatch, []string{"s1", "s2"}},
+			},
+		},
+		{
+			name: "empty files",
+			files: []*drive.File{
+				file("a", "p", "__init__.py", "d41d8cd98f00b204e9800998ecf8427e", 0, "2024-01-01T00:00:00Z"),
+				file("b", "q", "__init__.py", "d41d8cd98f00b204e9800998ecf8427e", 0, "2024-01-02T00:00:00Z"),
+				file("c", "r", ".keep", "d41d8cd98f00b204e9800998ecf8427e", 0, "2024-01-03T00:00:00Z"),
+			},
+		},
+		{
+			name: "empty files with the same name in a directory",
+			files: []*drive.File{
+				file("a", "p", ".keep", "d41d8cd98f00b204e9800998ecf8427e", 0, "2024-01-01T00:00:00Z"),
+				file("b", "p", ".keep", "d41d8cd98f00b204e9800998ecf8427e", 0, "2024-01-02T00:00:00Z"),
+			},
+			want: []group{{nameMatch, []string{"a", "b"}}},
+		},
+		{
+			name: "files without content",
+			files: []*drive.File{
+				file("a", "p", "Doc", "", 0, "2024-01-01T00:00:00Z"),
+				file("b", "q", "Doc", "", 0, "2024-01-02T00:00:00Z"),
+			},
+		},
+		{
+			name: "same name with other content",
+			files: []*drive.File{
+				file("a", "p", "a.txt", "md5a", 5, "2024-01-01T00:00:00Z"),
+				file("b", "p", "a.txt", "md5b", 5, "2024-01-02T00:00:00Z"),
+			},
+			want: []group{{nameMatch, []string{"a", "b"}}},
+		},
+		{
+			name: "same name and content is only a content group",
+			files: []*drive.File{
+				file("a", "p", "a.txt", "md5", 5, "2024-01-01T00:00:00Z"),
+				file("b", "p", "a.txt", "md5", 5, "2024-01-02T00:00:00Z"),
+			},
+			want: []group{{contentMatch, []string{"a", "b"}}},
+		},
+		{
+			name: "same md5 with another size",
+			files: []*drive.File{
+				file("a", "p", "a.txt", "md5", 5, "2024-01-01T00:00:00Z"),
+				file("b", "q", "b.txt", "md5", 6, "2024-01-02T00:00:00Z"),
+			},
+		},
+		{
+			name: "encrypted files by plain content",
+			files: []*drive.File{
+				encrypted("a", "p", "a", "enc1", "plain", "5"),
+				encrypted("b", "q", "b", "enc2", "plain", "5"),
+				encrypted("c", "r", "c", "enc3", "other", "5"),
+			},
+			want: []group{{contentMatch, []string{"a", "b"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []group
+			for _, g := range findDuplicates(test.files) {
+				var ids []string
+				for _, df := range g.files {
+					ids = append(ids, df.file.Id)
+				}
+				got = append(got, group{g.match, ids})
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("findDuplicates() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
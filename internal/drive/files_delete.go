@@ -9,10 +9,15 @@ type DeleteArgs struct {
 	Out       io.Writer
 	Id        string
 	Recursive bool
+	Permanent bool
 }
 
 func (self *Drive) Delete(args DeleteArgs) error {
-	f, err := self.service.Files.Get(args.Id).Fields("name", "mimeType").Do()
+	if err := self.resolvePaths(&args.Id); err != nil {
+		return err
+	}
+
+	f, err := self.service.Files.Get(args.Id).SupportsAllDrives(true).Fields("name", "mimeType").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get file: %s", err)
 	}
@@ -21,17 +26,29 @@ func (self *Drive) Delete(args DeleteArgs) error {
 		return fmt.Errorf("'%s' is a directory, use the 'recursive' flag to delete directories", f.Name)
 	}
 
-	err = self.service.Files.Delete(args.Id).Do()
+	err = self.deleteFile(args.Id, args.Permanent)
 	if err != nil {
-		return fmt.Errorf("Failed to delete file: %s", err)
+		return err
 	}
 
-	fmt.Fprintf(args.Out, "Deleted '%s'\n", f.Name)
+	if args.Permanent {
+		fmt.Fprintf(args.Out, "Deleted '%s'\n", f.Name)
+	} else {
+		fmt.Fprintf(args.Out, "Trashed '%s'\n", f.Name)
+	}
 	return nil
 }
 
-func (self *Drive) deleteFile(fileId string) error {
-	err := self.service.Files.Delete(fileId).Do()
+// Moves the file to the trash, unless it should be deleted permanently
+func (self *Drive) deleteFile(fileId string, permanent bool) error {
+	if !permanent {
+		_, err := self.trashFile(fileId)
+		return err
+	}
+
+	err := retryBackendErrors(func() error {
+		return self.service.Files.Delete(fileId).SupportsAllDrives(true).Do()
+	})
 	if err != nil {
 		return fmt.Errorf("Failed to delete file: %s", err)
 	}
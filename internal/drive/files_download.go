@@ -1,10 +1,14 @@
 package drive
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/imzza/gdrive/internal/utils"
@@ -12,30 +16,57 @@ import (
 	"google.golang.org/api/googleapi"
 )
 
+var downloadFields = []googleapi.Field{"id", "name", "size", "mimeType", "md5Checksum", "sha1Checksum", "sha256Checksum", "modifiedTime", "shortcutDetails", "appProperties"}
+
 type DownloadArgs struct {
-	Out       io.Writer
-	Progress  io.Writer
-	Id        string
-	Path      string
-	Force     bool
-	Skip      bool
-	Recursive bool
-	Delete    bool
-	Stdout    bool
-	NoParent  bool
-	Timeout   time.Duration
+	Out             io.Writer
+	Progress        io.Writer
+	Id              string
+	Path            string
+	Force           bool
+	Skip            bool
+	Recursive       bool
+	Delete          bool
+	Permanent       bool
+	Stdout          bool
+	Archive         string
+	ExportDocs      bool
+	NoParent        bool
+	FollowShortcuts bool
+	Verify          bool
+	Timeout         time.Duration
+	Workers         int
 }
 
 func (self *Drive) Download(args DownloadArgs) error {
+	if err := self.resolvePaths(&args.Id); err != nil {
+		return err
+	}
+
+	if args.Archive != "" {
+		return self.downloadArchive(args)
+	}
+
 	if args.Recursive {
 		return self.downloadRecursive(args)
 	}
 
-	f, err := self.service.Files.Get(args.Id).Fields("id", "name", "size", "mimeType", "md5Checksum").Do()
+	f, err := self.service.Files.Get(args.Id).SupportsAllDrives(true).Fields(downloadFields...).Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get file: %s", err)
 	}
 
+	if isShortcut(f) {
+		if !args.FollowShortcuts {
+			return fmt.Errorf("'%s' is a shortcut, use --follow-shortcuts to download its target", f.Name)
+		}
+
+		f, err = self.followShortcut(f, downloadFields...)
+		if err != nil {
+			return err
+		}
+	}
+
 	if isDir(f) {
 		return fmt.Errorf("'%s' is a directory, use --recursive to download directories", f.Name)
 	}
@@ -54,9 +85,9 @@ func (self *Drive) Download(args DownloadArgs) error {
 	}
 
 	if args.Delete {
-		err = self.deleteFile(args.Id)
+		err = self.deleteFile(args.Id, args.Permanent)
 		if err != nil {
-			return fmt.Errorf("Failed to delete file: %s", err)
+			return err
 		}
 
 		if !args.Stdout {
@@ -67,19 +98,28 @@ func (self *Drive) Download(args DownloadArgs) error {
 }
 
 type DownloadQueryArgs struct {
-	Out       io.Writer
-	Progress  io.Writer
-	Query     string
-	Path      string
-	Force     bool
-	Skip      bool
-	Recursive bool
+	Out             io.Writer
+	Progress        io.Writer
+	Query           FileQuery
+	Path            string
+	Force           bool
+	Skip            bool
+	Recursive       bool
+	FollowShortcuts bool
+	Verify          bool
+	Workers         int
 }
 
 func (self *Drive) DownloadQuery(args DownloadQueryArgs) error {
+	query, err := self.buildQuery(args.Query)
+	if err != nil {
+		return err
+	}
+
 	listArgs := listAllFilesArgs{
-		query:  args.Query,
-		fields: []googleapi.Field{"nextPageToken", "files(id,name,mimeType,size,md5Checksum)"},
+		query:   query,
+		fields:  []googleapi.Field{"nextPageToken", googleapi.Field(fmt.Sprintf("files(%s)", googleapi.CombineFields(downloadFields)))},
+		minSize: args.Query.LargerThan,
 	}
 	files, err := self.listAllFiles(listArgs)
 	if err != nil {
@@ -87,34 +127,58 @@ func (self *Drive) DownloadQuery(args DownloadQueryArgs) error {
 	}
 
 	downloadArgs := DownloadArgs{
-		Out:      args.Out,
-		Progress: args.Progress,
-		Path:     args.Path,
-		Force:    args.Force,
-		Skip:     args.Skip,
+		Out:             args.Out,
+		Progress:        args.Progress,
+		Path:            args.Path,
+		Force:           args.Force,
+		Skip:            args.Skip,
+		FollowShortcuts: args.FollowShortcuts,
+		Verify:          args.Verify,
+		Workers:         args.Workers,
 	}
 
+	var jobs []*downloadJob
+
 	for _, f := range files {
-		if isDir(f) && args.Recursive {
-			err = self.downloadDirectory(f, downloadArgs)
-		} else if isBinary(f) {
-			_, _, err = self.downloadBinary(f, downloadArgs)
+		if isShortcut(f) && args.FollowShortcuts {
+			f, err = self.followShortcut(f, downloadFields...)
+			if err != nil {
+				return err
+			}
 		}
 
-		if err != nil {
-			return err
+		if isDir(f) && args.Recursive {
+			name, err := self.plainName(f)
+			if err != nil {
+				return err
+			}
+
+			dirJobs, err := self.prepareDirectoryDownload(f, filepath.Join(args.Path, name), downloadArgs, map[string]bool{})
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, dirJobs...)
+		} else if isBinary(f) {
+			jobs = append(jobs, &downloadJob{file: f, path: args.Path})
 		}
 	}
 
-	return nil
+	return self.downloadFiles(jobs, downloadArgs)
 }
 
 func (self *Drive) downloadRecursive(args DownloadArgs) error {
-	f, err := self.service.Files.Get(args.Id).Fields("id", "name", "size", "mimeType", "md5Checksum").Do()
+	f, err := self.service.Files.Get(args.Id).SupportsAllDrives(true).Fields(downloadFields...).Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get file: %s", err)
 	}
 
+	if isShortcut(f) && args.FollowShortcuts {
+		f, err = self.followShortcut(f, downloadFields...)
+		if err != nil {
+			return err
+		}
+	}
+
 	if isDir(f) {
 		return self.downloadDirectory(f, args)
 	} else if isBinary(f) {
@@ -126,58 +190,79 @@ func (self *Drive) downloadRecursive(args DownloadArgs) error {
 }
 
 func (self *Drive) downloadBinary(f *drive.File, args DownloadArgs) (int64, int64, error) {
-	// Get timeout reader wrapper and context
-	timeoutReaderWrapper, ctx := utils.GetTimeoutReaderWrapperContext(args.Timeout)
-
-	res, err := self.service.Files.Get(f.Id).Context(ctx).Download()
-	if err != nil {
-		if isTimeoutError(err) {
-			return 0, 0, fmt.Errorf("Failed to download file: timeout, no data was transferred for %v", args.Timeout)
+	var key *EncryptionKey
+	if isEncrypted(f) {
+		var err error
+		if key, err = self.decryptionKey(f); err != nil {
+			return 0, 0, err
 		}
-		return 0, 0, fmt.Errorf("Failed to download file: %s", err)
 	}
 
-	// Close body on function exit
-	defer res.Body.Close()
+	name, err := self.plainName(f)
+	if err != nil {
+		return 0, 0, err
+	}
 
 	// Path to file
-	fpath := filepath.Join(args.Path, f.Name)
+	fpath := filepath.Join(args.Path, name)
 
 	if !args.Stdout {
-		fmt.Fprintf(args.Out, "Downloading %s -> %s\n", f.Name, fpath)
+		fmt.Fprintf(args.Out, "Downloading %s -> %s\n", name, fpath)
 	}
 
 	return self.saveFile(saveFileArgs{
-		out:           args.Out,
-		body:          timeoutReaderWrapper(res.Body),
-		contentLength: res.ContentLength,
-		fpath:         fpath,
-		force:         args.Force,
-		skip:          args.Skip,
-		stdout:        args.Stdout,
-		progress:      args.Progress,
+		out: args.Out,
+		download: func(ctx context.Context, offset int64) (*http.Response, error) {
+			call := self.service.Files.Get(f.Id).SupportsAllDrives(true).Context(ctx)
+			setRangeHeader(call.Header(), offset)
+			return call.Download()
+		},
+		md5:        f.Md5Checksum,
+		sha1:       f.Sha1Checksum,
+		sha256:     f.Sha256Checksum,
+		size:       f.Size,
+		fpath:      fpath,
+		force:      args.Force,
+		skip:       args.Skip,
+		stdout:     args.Stdout,
+		verify:     args.Verify,
+		decryptKey: key,
+		progress:   args.Progress,
+		timeout:    args.Timeout,
 	})
 }
 
+// Starts a download of the remote content from the given byte offset
+type downloadFunc func(ctx context.Context, offset int64) (*http.Response, error)
+
 type saveFileArgs struct {
-	out           io.Writer
-	body          io.Reader
-	contentLength int64
-	fpath         string
-	force         bool
-	skip          bool
-	stdout        bool
-	progress      io.Writer
+	out      io.Writer
+	download downloadFunc
+	md5      string
+	sha1     string
+	sha256   string
+	size     int64
+	fpath    string
+	force    bool
+	skip     bool
+	stdout   bool
+	// The content is compared with the checksums after it is saved
+	verify bool
+	// Encrypted content is decrypted with this key while it is saved, nil for plain content
+	decryptKey *EncryptionKey
+	progress   io.Writer
+	timeout    time.Duration
 }
 
 func (self *Drive) saveFile(args saveFileArgs) (int64, int64, error) {
-	// Wrap response body in progress reader
-	srcReader := utils.GetProgressReader(args.body, args.progress, args.contentLength)
-
 	if args.stdout {
 		// Write file content to stdout
-		_, err := io.Copy(args.out, srcReader)
-		return 0, 0, err
+		checksums := newVerifyChecksums(args.verify)
+		_, err := self.copyContent(args.out, 0, args, checksums)
+		if err != nil {
+			return 0, 0, err
+		}
+		return 0, 0, verifyDownload(checksums, args)
 	}
 
 	// Check if file exists to force
@@ -197,64 +282,365 @@ func (self *Drive) saveFile(args saveFileArgs) (int64, int64, error) {
 	}
 
 	// Download to tmp file
-	tmpPath := args.fpath + ".incomplete"
+	tmpPath := args.fpath + IncompleteSuffix
+
+	// Downloads can only be resumed if we are able to tell that the remote file has not changed.
+	// The offset of encrypted content does not match the size of the decrypted file
+	resumable := args.md5 != "" && args.decryptKey == nil
+
+	// Continue from the end of a previous incomplete download of the same content
+	var offset int64
+	if resumable {
+		offset = incompleteDownloadOffset(tmpPath, args.md5, args.size)
+		if err := saveIncompleteDownloadState(tmpPath, args.md5, args.size); err != nil {
+			return 0, 0, fmt.Errorf("Failed to save download state: %s", err)
+		}
+	}
 
-	// Create new file
-	outFile, err := os.Create(tmpPath)
+	// Open tmp file and discard anything after the resume offset
+	outFile, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY, 0666)
 	if err != nil {
 		return 0, 0, fmt.Errorf("Unable to create new file: %s", err)
 	}
 
+	if err = outFile.Truncate(offset); err == nil {
+		_, err = outFile.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		outFile.Close()
+		return 0, 0, fmt.Errorf("Failed preparing file: %s", err)
+	}
+
+	if offset > 0 {
+		fmt.Fprintf(args.out, "Resuming download of %s from %s\n", filepath.Base(args.fpath), formatSize(offset, false))
+	}
+
+	// The part saved by a previous run is hashed from the tmp file
+	checksums := newVerifyChecksums(args.verify)
+	if checksums != nil && offset > 0 {
+		if err := checksums.hashFile(tmpPath, offset); err != nil {
+			outFile.Close()
+			return 0, 0, err
+		}
+	}
+
 	started := time.Now()
 
-	// Save file to disk
-	bytes, err := io.Copy(outFile, srcReader)
+	// Save file to disk, the last chunk has already been written if the offset is at the end
+	bytes := offset
+	if offset == 0 || offset < args.size {
+		bytes, err = self.copyContent(outFile, offset, args, checksums)
+	}
+
+	// Close File
+	outFile.Close()
+
 	if err != nil {
-		outFile.Close()
-		os.Remove(tmpPath)
+		// Keep tmp file so that the download can be resumed later
+		if !resumable {
+			os.Remove(tmpPath)
+		}
 		return 0, 0, fmt.Errorf("Failed saving file: %s", err)
 	}
 
+	if err := verifyDownload(checksums, args); err != nil {
+		// Damaged content must not be resumed, the tmp file is kept for inspection
+		removeIncompleteDownloadState(tmpPath)
+		return 0, 0, fmt.Errorf("%s, the downloaded content was kept in %s", err, tmpPath)
+	}
+
 	// Calculate average download rate
-	rate := calcRate(bytes, started, time.Now())
+	rate := calcRate(bytes-offset, started, time.Now())
 
-	// Close File
-	outFile.Close()
+	removeIncompleteDownloadState(tmpPath)
 
 	// Rename tmp file to proper filename
 	return bytes, rate, os.Rename(tmpPath, args.fpath)
 }
 
+// Writes the remote content to w, encrypted content is decrypted. The
+// remote content is hashed into checksums unless checksums is nil
+func (self *Drive) copyContent(w io.Writer, offset int64, args saveFileArgs, checksums *checksumWriter) (int64, error) {
+	var decrypter *decryptWriter
+	if args.decryptKey != nil {
+		decrypter = args.decryptKey.newDecryptWriter(w)
+		w = decrypter
+	}
+
+	if checksums != nil {
+		w = io.MultiWriter(w, checksums)
+	}
+
+	bytes, err := self.copyRemoteContent(w, offset, args)
+	if err != nil || decrypter == nil {
+		return bytes, err
+	}
+
+	return bytes, decrypter.Close()
+}
+
+// Returns a checksum writer when the content should be verified, otherwise nil
+func newVerifyChecksums(verify bool) *checksumWriter {
+	if !verify {
+		return nil
+	}
+	return newChecksumWriter()
+}
+
+func verifyDownload(checksums *checksumWriter, args saveFileArgs) error {
+	if checksums == nil {
+		return nil
+	}
+
+	if err := checksums.verify(args.md5, args.sha1, args.sha256); err != nil {
+		return fmt.Errorf("Failed to verify %s: %s", filepath.Base(args.fpath), err)
+	}
+
+	return nil
+}
+
+// Writes remote content starting at offset to w. Interrupted transfers
+// are retried from the last written byte. Returns the offset after the last written byte
+func (self *Drive) copyRemoteContent(w io.Writer, offset int64, args saveFileArgs) (int64, error) {
+	try := 0
+
+	for {
+		n, err := self.copyRemoteContentFrom(w, offset, args)
+		offset += n
+
+		if err == nil {
+			return offset, nil
+		}
+
+		if try >= MaxErrorRetries || !isRetryableTransferError(err) {
+			if isTimeoutError(err) {
+				return offset, fmt.Errorf("Failed to download file: timeout, no data was transferred for %v", args.timeout)
+			}
+			return offset, fmt.Errorf("Failed to download file: %s", err)
+		}
+
+		exponentialBackoffSleep(try)
+		try++
+	}
+}
+
+func (self *Drive) copyRemoteContentFrom(w io.Writer, offset int64, args saveFileArgs) (int64, error) {
+	// Get timeout reader wrapper and context
+	timeoutReaderWrapper, ctx := utils.GetTimeoutReaderWrapperContext(args.timeout)
+
+	res, err := args.download(ctx, offset)
+	if err != nil {
+		return 0, err
+	}
+
+	// Close body on function exit
+	defer res.Body.Close()
+
+	// Wrap response body in timeout reader
+	body := timeoutReaderWrapper(res.Body)
+	contentLength := res.ContentLength
+
+	// Skip content we already have if the range header was ignored
+	if offset > 0 && res.StatusCode != http.StatusPartialContent {
+		if _, err := io.CopyN(io.Discard, body, offset); err != nil {
+			return 0, err
+		}
+		contentLength -= offset
+	}
+
+	// Wrap body in progress reader
+	reader := utils.GetProgressReader(body, args.progress, contentLength)
+
+	return io.Copy(w, reader)
+}
+
+func setRangeHeader(header http.Header, offset int64) {
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+}
+
+const IncompleteSuffix = ".incomplete"
+const IncompleteStateSuffix = ".incomplete.json"
+
+// Describes the remote content an incomplete download belongs to
+type incompleteDownloadState struct {
+	Md5  string `json:"md5"`
+	Size int64  `json:"size"`
+}
+
+func incompleteDownloadStatePath(tmpPath string) string {
+	return strings.TrimSuffix(tmpPath, IncompleteSuffix) + IncompleteStateSuffix
+}
+
+// Returns the size of the incomplete download at tmpPath if it belongs to the
+// same remote content, otherwise 0
+func incompleteDownloadOffset(tmpPath, md5 string, size int64) int64 {
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		return 0
+	}
+
+	f, err := os.Open(incompleteDownloadStatePath(tmpPath))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	var state incompleteDownloadState
+	if err := json.NewDecoder(f).Decode(&state); err != nil {
+		return 0
+	}
+
+	if state.Md5 != md5 || state.Size != size || info.Size() > size {
+		return 0
+	}
+
+	return info.Size()
+}
+
+func saveIncompleteDownloadState(tmpPath, md5 string, size int64) error {
+	return utils.WriteJSON(incompleteDownloadStatePath(tmpPath), incompleteDownloadState{
+		Md5:  md5,
+		Size: size,
+	})
+}
+
+func removeIncompleteDownloadState(tmpPath string) {
+	os.Remove(incompleteDownloadStatePath(tmpPath))
+}
+
+// Returns true for a partial download and its state file. Files that only
+// end in the suffix are not downloads of ours and are returned as false
+func isIncompleteDownload(absPath string) bool {
+	tmpPath := absPath
+	if strings.HasSuffix(absPath, IncompleteStateSuffix) {
+		tmpPath = strings.TrimSuffix(absPath, IncompleteStateSuffix) + IncompleteSuffix
+	} else if !strings.HasSuffix(absPath, IncompleteSuffix) {
+		return false
+	}
+
+	return fileExists(tmpPath) && fileExists(incompleteDownloadStatePath(tmpPath))
+}
+
 func (self *Drive) downloadDirectory(parent *drive.File, args DownloadArgs) error {
+	path := args.Path
+	if !args.NoParent {
+		name, err := self.plainName(parent)
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(args.Path, name)
+	}
+
+	jobs, err := self.prepareDirectoryDownload(parent, path, args, map[string]bool{})
+	if err != nil {
+		return err
+	}
+
+	return self.downloadFiles(jobs, args)
+}
+
+type downloadJob struct {
+	file *drive.File
+	path string
+}
+
+// Walks the directory tree and creates the local directories, parents first.
+// Returns the files in the tree that should be downloaded. Ancestors holds the
+// ids of the directories above, a shortcut to one of them would never end
+func (self *Drive) prepareDirectoryDownload(parent *drive.File, path string, args DownloadArgs, ancestors map[string]bool) ([]*downloadJob, error) {
 	listArgs := listAllFilesArgs{
-		query:  fmt.Sprintf("'%s' in parents", parent.Id),
-		fields: []googleapi.Field{"nextPageToken", "files(id,name)"},
+		query:  fmt.Sprintf("'%s' in parents and trashed = false", parent.Id),
+		fields: []googleapi.Field{"nextPageToken", googleapi.Field(fmt.Sprintf("files(%s)", googleapi.CombineFields(downloadFields)))},
 	}
 	files, err := self.listAllFiles(listArgs)
 	if err != nil {
-		return fmt.Errorf("Failed listing files: %s", err)
+		return nil, fmt.Errorf("Failed listing files: %s", err)
 	}
 
-	newPath := args.Path
-	if !args.NoParent {
-		newPath = filepath.Join(args.Path, parent.Name)
+	if path != "" {
+		if err := os.MkdirAll(path, 0775); err != nil {
+			return nil, fmt.Errorf("Failed to create directory: %s", err)
+		}
 	}
 
-	for _, f := range files {
-		// Copy args and update changed fields
-		newArgs := args
-		newArgs.Path = newPath
-		newArgs.NoParent = false
-		newArgs.Id = f.Id
-		newArgs.Stdout = false
+	var jobs []*downloadJob
+
+	ancestors[parent.Id] = true
+	defer delete(ancestors, parent.Id)
 
-		err = self.downloadRecursive(newArgs)
+	for _, f := range files {
+		f, err = self.downloadTarget(f, filepath.Join(path, f.Name), args, ancestors)
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		if f == nil {
+			continue
+		}
+
+		if isDir(f) {
+			name, err := self.plainName(f)
+			if err != nil {
+				return nil, err
+			}
+
+			dirJobs, err := self.prepareDirectoryDownload(f, filepath.Join(path, name), args, ancestors)
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, dirJobs...)
+		} else if isBinary(f) {
+			jobs = append(jobs, &downloadJob{file: f, path: path})
 		}
 	}
 
-	return nil
+	return jobs, nil
+}
+
+// Returns the file that is downloaded in place of f, which is the target
+// when f is a shortcut that should be followed. Nil is returned for skipped shortcuts
+func (self *Drive) downloadTarget(f *drive.File, path string, args DownloadArgs, ancestors map[string]bool) (*drive.File, error) {
+	if !isShortcut(f) {
+		return f, nil
+	}
+
+	if !args.FollowShortcuts {
+		return nil, nil
+	}
+
+	target, err := self.followShortcut(f, downloadFields...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ancestors[target.Id] {
+		fmt.Fprintf(args.Out, "Skipping shortcut %s, it points to a directory above it\n", path)
+		return nil, nil
+	}
+
+	return target, nil
+}
+
+func (self *Drive) downloadFiles(jobs []*downloadJob, args DownloadArgs) error {
+	// Ensure that output lines from concurrent workers are not interleaved
+	args.Out = newSyncWriter(args.Out)
+
+	// Progress bars from several files at once are unreadable, hide them
+	if args.Workers > 1 {
+		args.Progress = io.Discard
+	}
+
+	return runWorkers(args.Workers, len(jobs), func(i int) error {
+		// Copy args and update changed fields
+		newArgs := args
+		newArgs.Path = jobs[i].path
+		newArgs.Stdout = false
+
+		_, _, err := self.downloadBinary(jobs[i].file, newArgs)
+		return err
+	})
 }
 
 func isDir(f *drive.File) bool {
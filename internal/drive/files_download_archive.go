@@ -0,0 +1,265 @@
+package drive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+type archiveEntry struct {
+	// Path of the entry in the archive
+	name string
+	file *drive.File
+}
+
+// Downloads a directory as a single archive, written to stdout or to a
+// file named after the directory. Google documents are exported into
+// the archive with their default export mime type if requested
+func (self *Drive) downloadArchive(args DownloadArgs) error {
+	if _, ok := archiveMimes[args.Archive]; !ok {
+		return fmt.Errorf("Unknown archive format '%s', use tar, tar.gz or zip", args.Archive)
+	}
+
+	f, err := self.service.Files.Get(args.Id).SupportsAllDrives(true).Fields(downloadFields...).Do()
+	if err != nil {
+		return fmt.Errorf("Failed to get file: %s", err)
+	}
+
+	if isShortcut(f) && args.FollowShortcuts {
+		f, err = self.followShortcut(f, downloadFields...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !isDir(f) {
+		return fmt.Errorf("'%s' is not a directory, only directories can be archived", f.Name)
+	}
+
+	name, err := self.plainName(f)
+	if err != nil {
+		return err
+	}
+
+	if args.Stdout {
+		// Messages would end up in the archive
+		out := args.Out
+		args.Out = io.Discard
+
+		entries, err := self.prepareArchiveDownload(f, name, args, map[string]bool{})
+		if err != nil {
+			return err
+		}
+		return self.writeRemoteArchive(out, entries, args)
+	}
+
+	fpath := filepath.Join(args.Path, name+"."+args.Archive)
+
+	if args.Skip && fileExists(fpath) {
+		fmt.Fprintf(args.Out, "File '%s' already exists, skipping\n", fpath)
+		return nil
+	}
+
+	if !args.Force && fileExists(fpath) {
+		return fmt.Errorf("File '%s' already exists, use --force to overwrite or --skip to skip", fpath)
+	}
+
+	entries, err := self.prepareArchiveDownload(f, name, args, map[string]bool{})
+	if err != nil {
+		return err
+	}
+
+	if err := mkdir(fpath); err != nil {
+		return err
+	}
+
+	// Write to tmp file
+	tmpPath := fpath + IncompleteSuffix
+
+	outFile, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("Unable to create new file: %s", err)
+	}
+
+	fmt.Fprintf(args.Out, "Archiving %s -> %s\n", name, fpath)
+	started := time.Now()
+
+	err = self.writeRemoteArchive(outFile, entries, args)
+	outFile.Close()
+
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		return fmt.Errorf("Failed stat file: %s", err)
+	}
+
+	// Calculate average download rate
+	rate := calcRate(info.Size(), started, time.Now())
+
+	fmt.Fprintf(args.Out, "Downloaded %s at %s/s, total %s\n", f.Id, formatSize(rate, false), formatSize(info.Size(), false))
+
+	// Rename tmp file to proper filename
+	return os.Rename(tmpPath, fpath)
+}
+
+// Walks the directory tree and returns the directories and files that go
+// into the archive, parents first. Ancestors holds the ids of the
+// directories above, a shortcut to one of them would never end
+func (self *Drive) prepareArchiveDownload(parent *drive.File, name string, args DownloadArgs, ancestors map[string]bool) ([]*archiveEntry, error) {
+	listArgs := listAllFilesArgs{
+		query:  fmt.Sprintf("'%s' in parents and trashed = false", parent.Id),
+		fields: []googleapi.Field{"nextPageToken", googleapi.Field(fmt.Sprintf("files(%s)", googleapi.CombineFields(downloadFields)))},
+	}
+	files, err := self.listAllFiles(listArgs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed listing files: %s", err)
+	}
+
+	entries := []*archiveEntry{{name: name, file: parent}}
+
+	ancestors[parent.Id] = true
+	defer delete(ancestors, parent.Id)
+
+	for _, f := range files {
+		f, err = self.downloadTarget(f, path.Join(name, f.Name), args, ancestors)
+		if err != nil {
+			return nil, err
+		}
+
+		if f == nil {
+			continue
+		}
+
+		fname, err := self.plainName(f)
+		if err != nil {
+			return nil, err
+		}
+		fpath := path.Join(name, fname)
+
+		if isDir(f) {
+			dirEntries, err := self.prepareArchiveDownload(f, fpath, args, ancestors)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, dirEntries...)
+		} else if isBinary(f) {
+			entries = append(entries, &archiveEntry{name: fpath, file: f})
+		} else if exportMime, ok := DefaultExportMime[f.MimeType]; ok && args.ExportDocs {
+			entries = append(entries, &archiveEntry{name: getExportFilename(fpath, exportMime), file: f})
+		}
+	}
+
+	return entries, nil
+}
+
+func (self *Drive) writeRemoteArchive(w io.Writer, entries []*archiveEntry, args DownloadArgs) error {
+	archive, err := newArchiveWriter(w, args.Archive)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if isDir(entry.file) {
+			err = archive.writeDir(entry.name, parseModifiedTime(entry.file))
+		} else if isBinary(entry.file) {
+			err = self.archiveRemoteFile(archive, entry, args)
+		} else {
+			err = self.archiveExport(archive, entry)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// Writes the content of a binary file to the archive, encrypted files are decrypted
+func (self *Drive) archiveRemoteFile(archive archiveWriter, entry *archiveEntry, args DownloadArgs) error {
+	f := entry.file
+
+	size := f.Size
+	var key *EncryptionKey
+	if isEncrypted(f) {
+		var err error
+		if key, err = self.decryptionKey(f); err != nil {
+			return err
+		}
+		size = plainSize(f)
+	}
+
+	fmt.Fprintf(args.Out, "Adding %s\n", entry.name)
+
+	w, err := archive.createFile(entry.name, size, parseModifiedTime(f))
+	if err != nil {
+		return fmt.Errorf("Failed to archive '%s': %s", entry.name, err)
+	}
+
+	saveArgs := saveFileArgs{
+		out: args.Out,
+		download: func(ctx context.Context, offset int64) (*http.Response, error) {
+			call := self.service.Files.Get(f.Id).SupportsAllDrives(true).Context(ctx)
+			setRangeHeader(call.Header(), offset)
+			return call.Download()
+		},
+		md5:        f.Md5Checksum,
+		sha1:       f.Sha1Checksum,
+		sha256:     f.Sha256Checksum,
+		fpath:      entry.name,
+		decryptKey: key,
+		progress:   args.Progress,
+		timeout:    args.Timeout,
+	}
+
+	checksums := newVerifyChecksums(args.Verify)
+
+	_, err = self.copyContent(w, 0, saveArgs, checksums)
+	if err != nil {
+		return fmt.Errorf("Failed to archive '%s': %s", entry.name, err)
+	}
+
+	return verifyDownload(checksums, saveArgs)
+}
+
+// Exports a google document into the archive. The size of an export is
+// not known before it is done, exports are limited to 10 MB by drive so
+// they are held in memory
+func (self *Drive) archiveExport(archive archiveWriter, entry *archiveEntry) error {
+	exportMime := DefaultExportMime[entry.file.MimeType]
+
+	res, err := self.service.Files.Export(entry.file.Id, exportMime).Download()
+	if err != nil {
+		return fmt.Errorf("Failed to export file: %s", err)
+	}
+
+	// Close body on function exit
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("Failed to export file: %s", err)
+	}
+
+	w, err := archive.createFile(entry.name, int64(len(content)), parseModifiedTime(entry.file))
+	if err == nil {
+		_, err = w.Write(content)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to archive '%s': %s", entry.name, err)
+	}
+
+	return nil
+}
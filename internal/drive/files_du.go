@@ -0,0 +1,182 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"text/tabwriter"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+var diskUsageFields = []googleapi.Field{"id", "name", "mimeType", "size", "quotaBytesUsed", "appProperties"}
+
+type DiskUsageArgs struct {
+	Out io.Writer
+	Id  string
+	// Levels below the directory that are shown, a negative depth shows all
+	Depth       int64
+	SkipHeader  bool
+	SizeInBytes bool
+	Output      OutputFormat
+}
+
+type usageEntry struct {
+	file  *drive.File
+	path  string
+	depth int64
+	size  int64
+	quota int64
+	// Number of files in a directory and its subdirectories
+	count int64
+	// Biggest first, only kept down to the depth that is shown
+	children []*usageEntry
+}
+
+// Sums the size and the quota used by the files below a directory, and
+// prints the directory and its biggest directories and files in the spirit
+// of du. Shortcuts are not followed and trashed files are not counted
+func (self *Drive) DiskUsage(args DiskUsageArgs) error {
+	if err := self.resolvePaths(&args.Id); err != nil {
+		return err
+	}
+
+	fields := append([]googleapi.Field{"driveId"}, diskUsageFields...)
+	f, err := self.service.Files.Get(args.Id).SupportsAllDrives(true).Fields(fields...).Do()
+	if err != nil {
+		return fmt.Errorf("Failed to get file: %s", err)
+	}
+
+	root := &usageEntry{file: f, path: self.usageName(f)}
+
+	if isDir(f) {
+		// A directory in a shared drive is only listed when searching that drive
+		driveId := ""
+		if self.driveId == "" {
+			driveId = f.DriveId
+		}
+
+		if err := self.sumUsage(root, args.Depth, driveId, map[string]bool{f.Id: true}); err != nil {
+			return err
+		}
+	} else {
+		root.size, root.quota, root.count = f.Size, f.QuotaBytesUsed, 1
+	}
+
+	return printUsage(args, root)
+}
+
+// Walks the directory tree and adds up the usage of the files below
+// entry. Files with several parents are counted once
+func (self *Drive) sumUsage(entry *usageEntry, maxDepth int64, driveId string, seen map[string]bool) error {
+	listArgs := listAllFilesArgs{
+		query:   fmt.Sprintf("'%s' in parents and trashed = false", entry.file.Id),
+		fields:  []googleapi.Field{"nextPageToken", googleapi.Field(fmt.Sprintf("files(%s)", googleapi.CombineFields(diskUsageFields)))},
+		driveId: driveId,
+	}
+	files, err := self.listAllFiles(listArgs)
+	if err != nil {
+		return fmt.Errorf("Failed listing files: %s", err)
+	}
+
+	// Children below the depth that is shown are only counted
+	keepChildren := maxDepth < 0 || entry.depth < maxDepth
+
+	for _, f := range files {
+		if seen[f.Id] {
+			continue
+		}
+		seen[f.Id] = true
+
+		child := &usageEntry{
+			file:  f,
+			path:  path.Join(entry.path, self.usageName(f)),
+			depth: entry.depth + 1,
+		}
+
+		if isDir(f) {
+			if err := self.sumUsage(child, maxDepth, driveId, seen); err != nil {
+				return err
+			}
+		} else {
+			child.size, child.quota, child.count = f.Size, f.QuotaBytesUsed, 1
+		}
+
+		entry.size += child.size
+		entry.quota += child.quota
+		entry.count += child.count
+
+		if keepChildren {
+			entry.children = append(entry.children, child)
+		}
+	}
+
+	sort.SliceStable(entry.children, func(i, j int) bool {
+		return entry.children[i].size > entry.children[j].size
+	})
+
+	return nil
+}
+
+// Encrypted names are shown decrypted when the key is given, and as they are stored otherwise
+func (self *Drive) usageName(f *drive.File) string {
+	name, err := self.plainName(f)
+	if err != nil {
+		return f.Name
+	}
+	return name
+}
+
+// Returns the entry followed by the entries below it, depth first
+func (self *usageEntry) flatten() []*usageEntry {
+	entries := []*usageEntry{self}
+	for _, child := range self.children {
+		entries = append(entries, child.flatten()...)
+	}
+	return entries
+}
+
+func printUsage(args DiskUsageArgs, root *usageEntry) error {
+	entries := root.flatten()
+
+	if args.Output != TableOutput {
+		var records []outputRecord
+		for _, e := range entries {
+			records = append(records, outputRecord{
+				{"id", e.file.Id},
+				{"path", e.path},
+				{"type", filetype(e.file)},
+				{"depth", e.depth},
+				{"size", e.size},
+				{"quotaBytesUsed", e.quota},
+				{"files", e.count},
+			})
+		}
+		return writeRecords(args.Out, args.Output, args.SkipHeader, records)
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(args.Out, 0, 0, 3, ' ', 0)
+
+	if !args.SkipHeader {
+		fmt.Fprintln(w, "Size\tQuota used\tFiles\tPath")
+	}
+
+	for _, e := range entries {
+		name := e.path
+		if isDir(e.file) {
+			name += "/"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n",
+			formatSize(e.size, args.SizeInBytes),
+			formatSize(e.quota, args.SizeInBytes),
+			e.count,
+			name,
+		)
+	}
+
+	return w.Flush()
+}
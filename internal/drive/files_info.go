@@ -3,6 +3,7 @@ package drive
 import (
 	"fmt"
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"io"
 )
 
@@ -10,14 +11,36 @@ type FileInfoArgs struct {
 	Out         io.Writer
 	Id          string
 	SizeInBytes bool
+	Output      OutputFormat
+	Format      string
 }
 
 func (self *Drive) Info(args FileInfoArgs) error {
-	f, err := self.service.Files.Get(args.Id).Fields("id", "name", "size", "createdTime", "modifiedTime", "md5Checksum", "mimeType", "parents", "shared", "description", "webContentLink", "webViewLink").Do()
+	if err := self.resolvePaths(&args.Id); err != nil {
+		return err
+	}
+
+	tmpl, err := self.newFormatTemplate(args.Format)
+	if err != nil {
+		return err
+	}
+
+	fields := []googleapi.Field{"id", "name", "size", "createdTime", "modifiedTime", "md5Checksum", "mimeType", "parents", "shared", "description", "webContentLink", "webViewLink", "shortcutDetails"}
+
+	// Any field may be used in the format template
+	if tmpl != nil {
+		fields = []googleapi.Field{"*"}
+	}
+
+	f, err := self.service.Files.Get(args.Id).SupportsAllDrives(true).Fields(fields...).Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get file: %s", err)
 	}
 
+	if tmpl != nil {
+		return printFormatted(args.Out, tmpl, f)
+	}
+
 	pathfinder := self.newPathfinder()
 	absPath, err := pathfinder.absPath(f)
 	if err != nil {
@@ -29,6 +52,7 @@ func (self *Drive) Info(args FileInfoArgs) error {
 		File:        f,
 		Path:        absPath,
 		SizeInBytes: args.SizeInBytes,
+		Output:      args.Output,
 	})
 
 	return nil
@@ -39,11 +63,39 @@ type PrintFileInfoArgs struct {
 	File        *drive.File
 	Path        string
 	SizeInBytes bool
+	Output      OutputFormat
 }
 
 func PrintFileInfo(args PrintFileInfoArgs) {
 	f := args.File
 
+	var targetId, targetMimeType string
+	if f.ShortcutDetails != nil {
+		targetId = f.ShortcutDetails.TargetId
+		targetMimeType = f.ShortcutDetails.TargetMimeType
+	}
+
+	if args.Output != TableOutput {
+		writeRecord(args.Out, args.Output, outputRecord{
+			{"id", f.Id},
+			{"name", f.Name},
+			{"path", args.Path},
+			{"description", f.Description},
+			{"mimeType", f.MimeType},
+			{"size", f.Size},
+			{"createdTime", f.CreatedTime},
+			{"modifiedTime", f.ModifiedTime},
+			{"md5Checksum", f.Md5Checksum},
+			{"shared", f.Shared},
+			{"parents", nonNilList(f.Parents)},
+			{"webViewLink", f.WebViewLink},
+			{"webContentLink", f.WebContentLink},
+			{"shortcutTargetId", targetId},
+			{"shortcutTargetMimeType", targetMimeType},
+		})
+		return
+	}
+
 	items := []kv{
 		kv{"Id", f.Id},
 		kv{"Name", f.Name},
@@ -58,6 +110,8 @@ func PrintFileInfo(args PrintFileInfoArgs) {
 		kv{"Parents", formatList(f.Parents)},
 		kv{"ViewUrl", f.WebViewLink},
 		kv{"DownloadUrl", f.WebContentLink},
+		kv{"Target", targetId},
+		kv{"TargetMime", targetMimeType},
 	}
 
 	for _, item := range items {
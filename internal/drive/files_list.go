@@ -13,19 +13,37 @@ type ListFilesArgs struct {
 	Out         io.Writer
 	MaxFiles    int64
 	NameWidth   int64
-	Query       string
+	Query       FileQuery
 	SortOrder   string
 	SkipHeader  bool
 	SizeInBytes bool
 	AbsPath     bool
+	Output      OutputFormat
+	Format      string
 }
 
 func (self *Drive) List(args ListFilesArgs) (err error) {
+	tmpl, err := self.newFormatTemplate(args.Format)
+	if err != nil {
+		return err
+	}
+
+	query, err := self.buildQuery(args.Query)
+	if err != nil {
+		return err
+	}
+
 	listArgs := listAllFilesArgs{
-		query:     args.Query,
+		query:     query,
 		fields:    []googleapi.Field{"nextPageToken", "files(id,name,md5Checksum,mimeType,size,createdTime,parents)"},
 		sortOrder: args.SortOrder,
 		maxFiles:  args.MaxFiles,
+		minSize:   args.Query.LargerThan,
+	}
+
+	// Any field may be used in the format template
+	if tmpl != nil {
+		listArgs.fields = []googleapi.Field{"nextPageToken", "files(*)"}
 	}
 	files, err := self.listAllFiles(listArgs)
 	if err != nil {
@@ -44,12 +62,22 @@ func (self *Drive) List(args ListFilesArgs) (err error) {
 		}
 	}
 
+	if tmpl != nil {
+		for _, f := range files {
+			if err := printFormatted(args.Out, tmpl, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	PrintFileList(PrintFileListArgs{
 		Out:         args.Out,
 		Files:       files,
 		NameWidth:   int(args.NameWidth),
 		SkipHeader:  args.SkipHeader,
 		SizeInBytes: args.SizeInBytes,
+		Output:      args.Output,
 	})
 
 	return
@@ -60,6 +88,10 @@ type listAllFilesArgs struct {
 	fields    []googleapi.Field
 	sortOrder string
 	maxFiles  int64
+	// Files of this size or smaller are left out, the query language can not compare sizes
+	minSize int64
+	// Shared drive to search instead of the selected drive
+	driveId string
 }
 
 func (self *Drive) listAllFiles(args listAllFilesArgs) ([]*drive.File, error) {
@@ -74,8 +106,18 @@ func (self *Drive) listAllFiles(args listAllFilesArgs) ([]*drive.File, error) {
 
 	controlledStop := fmt.Errorf("Controlled stop")
 
-	err := self.service.Files.List().Q(args.query).Fields(args.fields...).OrderBy(args.sortOrder).PageSize(pageSize).Pages(context.TODO(), func(fl *drive.FileList) error {
-		files = append(files, fl.Files...)
+	call := self.newFilesListCall()
+	if args.driveId != "" {
+		call = call.Corpora("drive").DriveId(args.driveId).IncludeItemsFromAllDrives(true)
+	}
+
+	err := call.Q(args.query).Fields(args.fields...).OrderBy(args.sortOrder).PageSize(pageSize).Pages(context.TODO(), func(fl *drive.FileList) error {
+		for _, f := range fl.Files {
+			if args.minSize > 0 && f.Size <= args.minSize {
+				continue
+			}
+			files = append(files, f)
+		}
 
 		// Stop when we have all the files we need
 		if args.maxFiles > 0 && len(files) >= int(args.maxFiles) {
@@ -103,9 +145,19 @@ type PrintFileListArgs struct {
 	NameWidth   int
 	SkipHeader  bool
 	SizeInBytes bool
+	Output      OutputFormat
 }
 
 func PrintFileList(args PrintFileListArgs) {
+	if args.Output != TableOutput {
+		var records []outputRecord
+		for _, f := range args.Files {
+			records = append(records, fileRecord(f))
+		}
+		writeRecords(args.Out, args.Output, args.SkipHeader, records)
+		return
+	}
+
 	w := new(tabwriter.Writer)
 	w.Init(args.Out, 0, 0, 3, ' ', 0)
 
@@ -126,9 +178,24 @@ func PrintFileList(args PrintFileListArgs) {
 	w.Flush()
 }
 
+func fileRecord(f *drive.File) outputRecord {
+	return outputRecord{
+		{"id", f.Id},
+		{"name", f.Name},
+		{"type", filetype(f)},
+		{"mimeType", f.MimeType},
+		{"size", f.Size},
+		{"md5Checksum", f.Md5Checksum},
+		{"createdTime", f.CreatedTime},
+		{"parents", nonNilList(f.Parents)},
+	}
+}
+
 func filetype(f *drive.File) string {
 	if isDir(f) {
 		return "dir"
+	} else if isShortcut(f) {
+		return "shortcut"
 	} else if isBinary(f) {
 		return "bin"
 	}
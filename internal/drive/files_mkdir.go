@@ -13,9 +13,17 @@ type MkdirArgs struct {
 	Name        string
 	Description string
 	Parents     []string
+	// Store the name encrypted, see --encrypt-names
+	EncryptName bool
 }
 
 func (self *Drive) Mkdir(args MkdirArgs) error {
+	parents, err := self.resolveIds(args.Parents)
+	if err != nil {
+		return err
+	}
+	args.Parents = parents
+
 	f, err := self.mkdir(args)
 	if err != nil {
 		return err
@@ -32,10 +40,16 @@ func (self *Drive) mkdir(args MkdirArgs) (*drive.File, error) {
 	}
 
 	// Set parent folders
-	dstFile.Parents = args.Parents
+	dstFile.Parents = self.defaultParents(args.Parents)
+
+	if args.EncryptName {
+		if err := self.setEncryptedName(dstFile); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create directory
-	f, err := self.service.Files.Create(dstFile).Do()
+	f, err := self.service.Files.Create(dstFile).SupportsAllDrives(true).Do()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create directory: %s", err)
 	}
@@ -15,7 +15,11 @@ type MoveArgs struct {
 }
 
 func (self *Drive) Move(args MoveArgs) error {
-	f, err := self.service.Files.Get(args.Id).Fields("name,parents").Do()
+	if err := self.resolvePaths(&args.Id, &args.FolderId); err != nil {
+		return err
+	}
+
+	f, err := self.service.Files.Get(args.Id).SupportsAllDrives(true).Fields("name,parents").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get file: %s", err)
 	}
@@ -25,12 +29,12 @@ func (self *Drive) Move(args MoveArgs) error {
 		return err
 	}
 
-	oldParent, err := self.service.Files.Get(oldParentId).Fields("name").Do()
+	oldParent, err := self.service.Files.Get(oldParentId).SupportsAllDrives(true).Fields("name").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get old parent '%s': %s", oldParentId, err)
 	}
 
-	newParent, err := self.service.Files.Get(args.FolderId).Fields("name,mimeType").Do()
+	newParent, err := self.service.Files.Get(args.FolderId).SupportsAllDrives(true).Fields("name,mimeType").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get new parent: %s", err)
 	}
@@ -53,7 +53,7 @@ func (self *remotePathfinder) getParent(id string) (*drive.File, error) {
 	}
 
 	// Fetch file from drive
-	f, err := self.service.Get(id).Fields("id", "name", "parents").Do()
+	f, err := self.service.Get(id).SupportsAllDrives(true).Fields("id", "name", "parents").Do()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get file: %s", err)
 	}
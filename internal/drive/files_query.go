@@ -0,0 +1,125 @@
+package drive
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const DefaultQuery = "trashed = false and 'me' in owners"
+
+// Mime type conditions for the file types that can be searched for
+var fileTypeQueries = map[string]string{
+	"dir":      fmt.Sprintf("mimeType = '%s'", DirectoryMimeType),
+	"file":     "not mimeType contains 'application/vnd.google-apps.'",
+	"doc":      "mimeType = 'application/vnd.google-apps.document'",
+	"sheet":    "mimeType = 'application/vnd.google-apps.spreadsheet'",
+	"slides":   "mimeType = 'application/vnd.google-apps.presentation'",
+	"shortcut": fmt.Sprintf("mimeType = '%s'", ShortcutMimeType),
+}
+
+// Search conditions that are compiled into a files query. A raw query is
+// combined with the other conditions. Without any conditions the default
+// query is used
+type FileQuery struct {
+	Query         string
+	Name          string
+	NameContains  string
+	Parent        string
+	Type          string
+	ModifiedAfter string
+	LargerThan    int64
+	Starred       bool
+	SharedWithMe  bool
+	Trashed       bool
+	FullText      string
+	Owner         string
+}
+
+func (self *Drive) buildQuery(q FileQuery) (string, error) {
+	var conditions []string
+
+	if q.Query != "" {
+		conditions = append(conditions, "("+q.Query+")")
+	}
+
+	if q.Name != "" {
+		conditions = append(conditions, fmt.Sprintf("name = '%s'", escapeQueryValue(q.Name)))
+	}
+
+	if q.NameContains != "" {
+		conditions = append(conditions, fmt.Sprintf("name contains '%s'", escapeQueryValue(q.NameContains)))
+	}
+
+	if q.Parent != "" {
+		parentId, err := self.resolveId(q.Parent)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, fmt.Sprintf("'%s' in parents", escapeQueryValue(parentId)))
+	}
+
+	if q.Type != "" {
+		condition, ok := fileTypeQueries[q.Type]
+		if !ok {
+			return "", fmt.Errorf("Unknown file type '%s', must be one of dir, file, doc, sheet, slides or shortcut", q.Type)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if q.ModifiedAfter != "" {
+		modified, err := parseQueryTime(q.ModifiedAfter)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, fmt.Sprintf("modifiedTime > '%s'", modified.UTC().Format(time.RFC3339)))
+	}
+
+	if q.Starred {
+		conditions = append(conditions, "starred = true")
+	}
+
+	if q.SharedWithMe {
+		conditions = append(conditions, "sharedWithMe = true")
+	}
+
+	if q.FullText != "" {
+		conditions = append(conditions, fmt.Sprintf("fullText contains '%s'", escapeQueryValue(q.FullText)))
+	}
+
+	if q.Owner != "" {
+		conditions = append(conditions, fmt.Sprintf("'%s' in owners", escapeQueryValue(q.Owner)))
+	}
+
+	// Files in a shared drive are owned by the drive rather than a user,
+	// so the owner condition of the default query would never match there
+	if len(conditions) == 0 && q.LargerThan == 0 && !q.Trashed {
+		if self.driveId != "" {
+			return "trashed = false", nil
+		}
+		return DefaultQuery, nil
+	}
+
+	// A raw query decides for itself whether to include trashed files
+	if q.Trashed {
+		conditions = append(conditions, "trashed = true")
+	} else if q.Query == "" {
+		conditions = append(conditions, "trashed = false")
+	}
+
+	return strings.Join(conditions, " and "), nil
+}
+
+// Accepts a date or a RFC3339 timestamp, dates are in local time
+func parseQueryTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid time '%s', use a date like 2006-01-02 or a timestamp like 2006-01-02T15:04:05Z", s)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,115 @@
+package drive
+
+import "testing"
+
+func TestEscapeQueryValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "report", want: "report"},
+		{value: "", want: ""},
+		{value: "John's file", want: `John\'s file`},
+		{value: `C:\dir`, want: `C:\\dir`},
+		{value: `\'`, want: `\\\'`},
+		{value: "''", want: `\'\'`},
+	}
+
+	for _, test := range tests {
+		if got := escapeQueryValue(test.value); got != test.want {
+			t.Errorf("escapeQueryValue(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		driveId string
+		query   FileQuery
+		want    string
+	}{
+		{
+			name:  "default",
+			query: FileQuery{},
+			want:  DefaultQuery,
+		},
+		{
+			name:    "default in a shared drive",
+			driveId: "drive",
+			query:   FileQuery{},
+			want:    "trashed = false",
+		},
+		{
+			name:  "raw query",
+			query: FileQuery{Query: "name = 'a' or name = 'b'"},
+			want:  "(name = 'a' or name = 'b')",
+		},
+		{
+			name:  "raw query with a condition",
+			query: FileQuery{Query: "starred = true", Type: "dir"},
+			want:  "(starred = true) and mimeType = 'application/vnd.google-apps.folder'",
+		},
+		{
+			name:  "escaped name",
+			query: FileQuery{Name: "John's"},
+			want:  `name = 'John\'s' and trashed = false`,
+		},
+		{
+			name:  "name contains and parent",
+			query: FileQuery{NameContains: "report", Parent: "parentId"},
+			want:  "name contains 'report' and 'parentId' in parents and trashed = false",
+		},
+		{
+			name:  "modified after a timestamp",
+			query: FileQuery{ModifiedAfter: "2024-01-02T03:04:05+02:00"},
+			want:  "modifiedTime > '2024-01-02T01:04:05Z' and trashed = false",
+		},
+		{
+			name:  "flags",
+			query: FileQuery{Starred: true, SharedWithMe: true, FullText: `a\b`, Owner: "me@example.com"},
+			want:  `starred = true and sharedWithMe = true and fullText contains 'a\\b' and 'me@example.com' in owners and trashed = false`,
+		},
+		{
+			name:  "trashed",
+			query: FileQuery{Trashed: true},
+			want:  "trashed = true",
+		},
+		{
+			name:  "size only",
+			query: FileQuery{LargerThan: 1024},
+			want:  "trashed = false",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &Drive{driveId: test.driveId}
+			got, err := d.buildQuery(test.query)
+			if err != nil {
+				t.Fatalf("buildQuery() failed: %s", err)
+			}
+			if got != test.want {
+				t.Errorf("buildQuery() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBuildQueryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query FileQuery
+	}{
+		{name: "unknown type", query: FileQuery{Type: "video"}},
+		{name: "invalid time", query: FileQuery{ModifiedAfter: "yesterday"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := (&Drive{}).buildQuery(test.query); err == nil {
+				t.Errorf("buildQuery() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package drive
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Prefixes of paths that can be given instead of file ids,
+// e.g. drive:/Projects/report.pdf or shared:Team/Projects/report.pdf
+const (
+	MyDrivePathPrefix     = "drive:"
+	SharedDrivePathPrefix = "shared:"
+)
+
+func isRemotePath(s string) bool {
+	return strings.HasPrefix(s, MyDrivePathPrefix) || strings.HasPrefix(s, SharedDrivePathPrefix)
+}
+
+// Returns the id of the file at the given path. Anything that is not a path is returned as is
+func (self *Drive) resolveId(idOrPath string) (string, error) {
+	if !isRemotePath(idOrPath) {
+		return idOrPath, nil
+	}
+
+	if self.resolver == nil {
+		self.resolver = self.newResolver()
+	}
+
+	return self.resolver.resolve(idOrPath)
+}
+
+// Replaces each path among the given ids with the id of the file it points to
+func (self *Drive) resolvePaths(idsOrPaths ...*string) error {
+	for _, idOrPath := range idsOrPaths {
+		id, err := self.resolveId(*idOrPath)
+		if err != nil {
+			return err
+		}
+		*idOrPath = id
+	}
+
+	return nil
+}
+
+func (self *Drive) resolveIds(idsOrPaths []string) ([]string, error) {
+	var ids []string
+
+	for _, idOrPath := range idsOrPaths {
+		id, err := self.resolveId(idOrPath)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (self *Drive) newResolver() *remoteResolver {
+	return &remoteResolver{
+		service:    self.service,
+		listDrives: self.listAllDrives,
+		files:      make(map[string]*drive.File),
+	}
+}
+
+// Finds files by path, the files found along the way are cached
+type remoteResolver struct {
+	service    *drive.Service
+	listDrives func() ([]*DriveInfo, error)
+	files      map[string]*drive.File
+	drives     []*DriveInfo
+}
+
+func (self *remoteResolver) resolve(path string) (string, error) {
+	var rootId, driveId string
+	var names []string
+
+	if strings.HasPrefix(path, SharedDrivePathPrefix) {
+		parts := splitRemotePath(strings.TrimPrefix(path, SharedDrivePathPrefix))
+		if len(parts) == 0 {
+			return "", fmt.Errorf("Missing shared drive name in '%s'", path)
+		}
+
+		id, err := self.findSharedDrive(parts[0])
+		if err != nil {
+			return "", err
+		}
+
+		rootId = id
+		driveId = id
+		names = parts[1:]
+	} else {
+		rootId = "root"
+		names = splitRemotePath(strings.TrimPrefix(path, MyDrivePathPrefix))
+	}
+
+	id := rootId
+	for i, name := range names {
+		f, err := self.findChild(driveId, id, name)
+		if err != nil {
+			return "", err
+		}
+
+		if f == nil {
+			return "", fmt.Errorf("File not found: '%s'", path)
+		}
+
+		if i < len(names)-1 && !isDir(f) {
+			return "", fmt.Errorf("'%s' in '%s' is not a directory", f.Name, path)
+		}
+
+		id = f.Id
+	}
+
+	return id, nil
+}
+
+// Finds the file with the given name in the parent directory,
+// nil is returned if there is none and an error if there are several
+func (self *remoteResolver) findChild(driveId, parentId, name string) (*drive.File, error) {
+	key := parentId + "/" + name
+
+	// Check cache
+	if f, ok := self.files[key]; ok {
+		return f, nil
+	}
+
+	query := fmt.Sprintf("name = '%s' and '%s' in parents and trashed = false", escapeQueryValue(name), parentId)
+	call := self.service.Files.List().Q(query).Fields("files(id,name,mimeType)").PageSize(10).SupportsAllDrives(true)
+	if driveId != "" {
+		call = call.Corpora("drive").DriveId(driveId).IncludeItemsFromAllDrives(true)
+	}
+
+	fileList, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find '%s': %s", name, err)
+	}
+
+	if len(fileList.Files) == 0 {
+		return nil, nil
+	}
+
+	if len(fileList.Files) > 1 {
+		var ids []string
+		for _, f := range fileList.Files {
+			ids = append(ids, f.Id)
+		}
+		return nil, fmt.Errorf("'%s' is ambiguous, it matches the files %s, use a file id instead", name, strings.Join(ids, ", "))
+	}
+
+	// Save in cache
+	f := fileList.Files[0]
+	self.files[key] = f
+
+	return f, nil
+}
+
+func (self *remoteResolver) findSharedDrive(name string) (string, error) {
+	if self.drives == nil {
+		drives, err := self.listDrives()
+		if err != nil {
+			return "", fmt.Errorf("Failed to list drives: %s", err)
+		}
+		self.drives = drives
+	}
+
+	var ids []string
+	for _, d := range self.drives {
+		if d.Name == name {
+			ids = append(ids, d.Id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return "", fmt.Errorf("Shared drive not found: '%s'", name)
+	}
+
+	if len(ids) > 1 {
+		return "", fmt.Errorf("Shared drive name '%s' is ambiguous, it matches the drives %s, use the drive id instead", name, strings.Join(ids, ", "))
+	}
+
+	return ids[0], nil
+}
+
+// Splits a path into its names, empty names from leading, trailing or double slashes are skipped
+func splitRemotePath(path string) []string {
+	var names []string
+
+	for _, name := range strings.Split(path, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func escapeQueryValue(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return strings.Replace(s, `'`, `\'`, -1)
+}
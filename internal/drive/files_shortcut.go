@@ -0,0 +1,82 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const ShortcutMimeType = "application/vnd.google-apps.shortcut"
+
+type CreateShortcutArgs struct {
+	Out      io.Writer
+	TargetId string
+	FolderId string
+	Name     string
+}
+
+func (self *Drive) CreateShortcut(args CreateShortcutArgs) error {
+	if err := self.resolvePaths(&args.TargetId, &args.FolderId); err != nil {
+		return err
+	}
+
+	target, err := self.service.Files.Get(args.TargetId).SupportsAllDrives(true).Fields("id", "name").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to get shortcut target: %s", err)
+	}
+
+	// Shortcuts are named after their target unless another name is given
+	name := args.Name
+	if name == "" {
+		name = target.Name
+	}
+
+	dstFile := &drive.File{
+		Name:            name,
+		MimeType:        ShortcutMimeType,
+		Parents:         []string{args.FolderId},
+		ShortcutDetails: &drive.FileShortcutDetails{TargetId: target.Id},
+	}
+
+	f, err := self.service.Files.Create(dstFile).SupportsAllDrives(true).Fields("id", "name").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to create shortcut: %s", err)
+	}
+
+	fmt.Fprintf(args.Out, "Shortcut %s created for '%s'\n", f.Id, target.Name)
+	return nil
+}
+
+func isShortcut(f *drive.File) bool {
+	return f.MimeType == ShortcutMimeType
+}
+
+// Returns the file the shortcut points to. The target is given the name of
+// the shortcut, so that it ends up where the shortcut is when downloaded.
+// The fields must include id, mimeType and shortcutDetails
+func (self *Drive) followShortcut(shortcut *drive.File, fields ...googleapi.Field) (*drive.File, error) {
+	f := shortcut
+	seen := map[string]bool{}
+
+	for isShortcut(f) {
+		if seen[f.Id] {
+			return nil, fmt.Errorf("Shortcut '%s' is part of a cycle of shortcuts", shortcut.Name)
+		}
+		seen[f.Id] = true
+
+		if f.ShortcutDetails == nil {
+			return nil, fmt.Errorf("Shortcut '%s' has no target", shortcut.Name)
+		}
+
+		target, err := self.service.Files.Get(f.ShortcutDetails.TargetId).SupportsAllDrives(true).Fields(fields...).Do()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get target of shortcut '%s': %s", shortcut.Name, err)
+		}
+		f = target
+	}
+
+	f.Name = shortcut.Name
+	return f, nil
+}
@@ -0,0 +1,106 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/api/drive/v3"
+)
+
+type TrashArgs struct {
+	Out io.Writer
+	Id  string
+}
+
+func (self *Drive) Trash(args TrashArgs) error {
+	if err := self.resolvePaths(&args.Id); err != nil {
+		return err
+	}
+
+	name, err := self.trashFile(args.Id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Trashed '%s'\n", name)
+	return nil
+}
+
+type UntrashArgs struct {
+	Out io.Writer
+	Id  string
+}
+
+func (self *Drive) Untrash(args UntrashArgs) error {
+	// Trashed files are not found by path, so only ids are accepted here
+	dstFile := &drive.File{Trashed: false, ForceSendFields: []string{"Trashed"}}
+
+	f, err := self.service.Files.Update(args.Id, dstFile).SupportsAllDrives(true).Fields("name").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to restore file: %s", err)
+	}
+
+	fmt.Fprintf(args.Out, "Restored '%s'\n", f.Name)
+	return nil
+}
+
+type ListTrashArgs struct {
+	Out         io.Writer
+	MaxFiles    int64
+	NameWidth   int64
+	SkipHeader  bool
+	SizeInBytes bool
+	Output      OutputFormat
+	Format      string
+}
+
+func (self *Drive) ListTrash(args ListTrashArgs) error {
+	// Only the owner can restore or delete a trashed file, except in shared drives where files have no owner
+	query := "trashed = true and 'me' in owners"
+	if self.driveId != "" {
+		query = "trashed = true"
+	}
+
+	return self.List(ListFilesArgs{
+		Out:         args.Out,
+		MaxFiles:    args.MaxFiles,
+		NameWidth:   args.NameWidth,
+		Query:       FileQuery{Query: query},
+		SortOrder:   "modifiedTime desc",
+		SkipHeader:  args.SkipHeader,
+		SizeInBytes: args.SizeInBytes,
+		Output:      args.Output,
+		Format:      args.Format,
+	})
+}
+
+type EmptyTrashArgs struct {
+	Out io.Writer
+}
+
+func (self *Drive) EmptyTrash(args EmptyTrashArgs) error {
+	call := self.service.Files.EmptyTrash()
+	if self.driveId != "" {
+		call = call.DriveId(self.driveId)
+	}
+
+	if err := call.Do(); err != nil {
+		return fmt.Errorf("Failed to empty trash: %s", err)
+	}
+
+	fmt.Fprintln(args.Out, "Trash emptied")
+	return nil
+}
+
+// Moves the file to the trash and returns its name
+func (self *Drive) trashFile(fileId string) (string, error) {
+	var f *drive.File
+	err := retryBackendErrors(func() (err error) {
+		f, err = self.service.Files.Update(fileId, &drive.File{Trashed: true}).SupportsAllDrives(true).Fields("name").Do()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to trash file: %s", err)
+	}
+	return f.Name, nil
+}
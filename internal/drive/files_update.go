@@ -13,17 +13,21 @@ import (
 )
 
 type UpdateArgs struct {
-	Out         io.Writer
-	Progress    io.Writer
-	Id          string
-	Path        string
-	Name        string
-	Description string
-	Parents     []string
-	Mime        string
-	Recursive   bool
-	ChunkSize   int64
-	Timeout     time.Duration
+	Out          io.Writer
+	Progress     io.Writer
+	Id           string
+	Path         string
+	Name         string
+	Description  string
+	Parents      []string
+	Mime         string
+	Recursive    bool
+	Verify       bool
+	Encrypt      bool
+	EncryptNames bool
+	ChunkSize    int64
+	Timeout      time.Duration
+	Sessions     *UploadSessionStore
 }
 
 func (self *Drive) Update(args UpdateArgs) error {
@@ -54,19 +58,62 @@ func (self *Drive) Update(args UpdateArgs) error {
 	// Set parent folders
 	dstFile.Parents = args.Parents
 
-	// Chunk size option
-	chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
-
-	// Wrap file in progress reader
-	progressReader := utils.GetProgressReader(srcFile, args.Progress, srcFileInfo.Size())
+	// Properties of a previous encrypted revision no longer apply
+	if !args.EncryptNames {
+		clearAppProperties(dstFile, encryptedNameProperty)
+	}
+	if !args.Encrypt {
+		clearContentEncryption(dstFile)
+	}
 
-	// Wrap reader in timeout reader
-	reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.Timeout)
+	fields := []googleapi.Field{"id", "name", "size", "md5Checksum", "sha1Checksum", "sha256Checksum"}
 
 	fmt.Fprintf(args.Out, "Uploading %s\n", args.Path)
 	started := time.Now()
 
-	f, err := self.service.Files.Update(args.Id, dstFile).Fields("id", "name", "size").Context(ctx).Media(reader, chunkSize).Do()
+	checksums := newVerifyChecksums(args.Verify)
+
+	var f *drive.File
+
+	if args.Encrypt {
+		f, err = self.uploadEncrypted(encryptedUploadArgs{
+			src:         srcFile,
+			size:        srcFileInfo.Size(),
+			file:        dstFile,
+			fileId:      args.Id,
+			fields:      fields,
+			encryptName: args.EncryptNames,
+			chunkSize:   args.ChunkSize,
+			progress:    args.Progress,
+			timeout:     args.Timeout,
+			checksums:   checksums,
+		})
+	} else if useResumableUpload(srcFileInfo.Size(), args.ChunkSize) {
+		f, err = self.resumableUpload(resumableUploadArgs{
+			out:       args.Out,
+			src:       srcFile,
+			info:      srcFileInfo,
+			file:      dstFile,
+			fileId:    args.Id,
+			fields:    fields,
+			chunkSize: args.ChunkSize,
+			progress:  args.Progress,
+			timeout:   args.Timeout,
+			sessions:  args.Sessions,
+			checksums: checksums,
+		})
+	} else {
+		// Chunk size option
+		chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
+
+		// Wrap file in progress reader
+		progressReader := utils.GetProgressReader(checksumReader(srcFile, checksums), args.Progress, srcFileInfo.Size())
+
+		// Wrap reader in timeout reader
+		reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.Timeout)
+
+		f, err = self.service.Files.Update(args.Id, dstFile).SupportsAllDrives(true).Fields(fields...).Context(ctx).Media(reader, chunkSize).Do()
+	}
 	if err != nil {
 		if isTimeoutError(err) {
 			return fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
@@ -74,6 +121,10 @@ func (self *Drive) Update(args UpdateArgs) error {
 		return fmt.Errorf("Failed to upload file: %s", err)
 	}
 
+	if err := verifyUpload(checksums, f); err != nil {
+		return err
+	}
+
 	// Calculate average upload rate
 	rate := calcRate(f.Size, started, time.Now())
 
@@ -14,21 +14,32 @@ import (
 )
 
 type UploadArgs struct {
-	Out         io.Writer
-	Progress    io.Writer
-	Path        string
-	Name        string
-	Description string
-	Parents     []string
-	Mime        string
-	Recursive   bool
-	Share       bool
-	Delete      bool
-	ChunkSize   int64
-	Timeout     time.Duration
+	Out          io.Writer
+	Progress     io.Writer
+	Path         string
+	Name         string
+	Description  string
+	Parents      []string
+	Mime         string
+	Recursive    bool
+	Archive      string
+	Share        bool
+	Delete       bool
+	Verify       bool
+	Encrypt      bool
+	EncryptNames bool
+	ChunkSize    int64
+	Timeout      time.Duration
+	Sessions     *UploadSessionStore
 }
 
 func (self *Drive) Upload(args UploadArgs) error {
+	parents, err := self.resolveIds(args.Parents)
+	if err != nil {
+		return err
+	}
+	args.Parents = parents
+
 	if args.ChunkSize > intMax()-1 {
 		return fmt.Errorf("Chunk size is to big, max chunk size for this computer is %d", intMax()-1)
 	}
@@ -45,6 +56,10 @@ func (self *Drive) Upload(args UploadArgs) error {
 		}
 	}
 
+	if args.Archive != "" {
+		return self.uploadArchive(args)
+	}
+
 	if args.Recursive {
 		return self.uploadRecursive(args)
 	}
@@ -117,6 +132,7 @@ func (self *Drive) uploadDirectory(args UploadArgs) error {
 		Name:        srcFileInfo.Name(),
 		Parents:     args.Parents,
 		Description: args.Description,
+		EncryptName: args.Encrypt && args.EncryptNames,
 	})
 	if err != nil {
 		return err
@@ -145,6 +161,79 @@ func (self *Drive) uploadDirectory(args UploadArgs) error {
 	return nil
 }
 
+// Streams a directory as an archive into a single file, files
+// ignored by the .gdriveignore in the directory are left out
+func (self *Drive) uploadArchive(args UploadArgs) error {
+	mimeType, ok := archiveMimes[args.Archive]
+	if !ok {
+		return fmt.Errorf("Unknown archive format '%s', use tar, tar.gz or zip", args.Archive)
+	}
+
+	// The archive is written while it is uploaded, so the directory must stay
+	if args.Delete {
+		return fmt.Errorf("--delete is not allowed for archive uploads")
+	}
+
+	absPath, err := filepath.Abs(args.Path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return fmt.Errorf("Failed stat file: %s", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is not a directory, only directories can be archived", info.Name())
+	}
+
+	files, err := prepareLocalFiles(absPath, args.Out)
+	if err != nil {
+		return err
+	}
+
+	name := args.Name
+	if name == "" {
+		name = info.Name() + "." + args.Archive
+	}
+
+	if args.Mime != "" {
+		mimeType = args.Mime
+	}
+
+	// The archive is written while it is uploaded
+	reader, writer := io.Pipe()
+	go func() {
+		archive, err := newArchiveWriter(writer, args.Archive)
+		if err == nil {
+			err = archiveLocalFiles(archive, info.Name(), info, files)
+		}
+		writer.CloseWithError(err)
+	}()
+
+	// Stops the archiving if the upload fails
+	defer reader.Close()
+
+	fmt.Fprintf(args.Out, "Archiving %s as %s\n", args.Path, args.Archive)
+
+	return self.UploadStream(UploadStreamArgs{
+		Out:          args.Out,
+		In:           reader,
+		Name:         name,
+		Description:  args.Description,
+		Parents:      args.Parents,
+		Mime:         mimeType,
+		Share:        args.Share,
+		Verify:       args.Verify,
+		Encrypt:      args.Encrypt,
+		EncryptNames: args.EncryptNames,
+		ChunkSize:    args.ChunkSize,
+		Progress:     args.Progress,
+		Timeout:      args.Timeout,
+	})
+}
+
 func (self *Drive) uploadFile(args UploadArgs) (*drive.File, int64, error) {
 	srcFile, srcFileInfo, err := openFile(args.Path)
 	if err != nil {
@@ -172,21 +261,54 @@ func (self *Drive) uploadFile(args UploadArgs) (*drive.File, int64, error) {
 	}
 
 	// Set parent folders
-	dstFile.Parents = args.Parents
-
-	// Chunk size option
-	chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
+	dstFile.Parents = self.defaultParents(args.Parents)
 
-	// Wrap file in progress reader
-	progressReader := utils.GetProgressReader(srcFile, args.Progress, srcFileInfo.Size())
-
-	// Wrap reader in timeout reader
-	reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.Timeout)
+	fields := []googleapi.Field{"id", "name", "size", "md5Checksum", "sha1Checksum", "sha256Checksum", "webContentLink"}
 
 	fmt.Fprintf(args.Out, "Uploading %s\n", args.Path)
 	started := time.Now()
 
-	f, err := self.service.Files.Create(dstFile).Fields("id", "name", "size", "md5Checksum", "webContentLink").Context(ctx).Media(reader, chunkSize).Do()
+	checksums := newVerifyChecksums(args.Verify)
+
+	var f *drive.File
+
+	if args.Encrypt {
+		f, err = self.uploadEncrypted(encryptedUploadArgs{
+			src:         srcFile,
+			size:        srcFileInfo.Size(),
+			file:        dstFile,
+			fields:      fields,
+			encryptName: args.EncryptNames,
+			chunkSize:   args.ChunkSize,
+			progress:    args.Progress,
+			timeout:     args.Timeout,
+			checksums:   checksums,
+		})
+	} else if useResumableUpload(srcFileInfo.Size(), args.ChunkSize) {
+		f, err = self.resumableUpload(resumableUploadArgs{
+			out:       args.Out,
+			src:       srcFile,
+			info:      srcFileInfo,
+			file:      dstFile,
+			fields:    fields,
+			chunkSize: args.ChunkSize,
+			progress:  args.Progress,
+			timeout:   args.Timeout,
+			sessions:  args.Sessions,
+			checksums: checksums,
+		})
+	} else {
+		// Chunk size option
+		chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
+
+		// Wrap file in progress reader
+		progressReader := utils.GetProgressReader(checksumReader(srcFile, checksums), args.Progress, srcFileInfo.Size())
+
+		// Wrap reader in timeout reader
+		reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.Timeout)
+
+		f, err = self.service.Files.Create(dstFile).SupportsAllDrives(true).Fields(fields...).Context(ctx).Media(reader, chunkSize).Do()
+	}
 	if err != nil {
 		if isTimeoutError(err) {
 			return nil, 0, fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
@@ -194,6 +316,10 @@ func (self *Drive) uploadFile(args UploadArgs) (*drive.File, int64, error) {
 		return nil, 0, fmt.Errorf("Failed to upload file: %s", err)
 	}
 
+	if err := verifyUpload(checksums, f); err != nil {
+		return nil, 0, err
+	}
+
 	// Calculate average upload rate
 	rate := calcRate(f.Size, started, time.Now())
 
@@ -201,19 +327,28 @@ func (self *Drive) uploadFile(args UploadArgs) (*drive.File, int64, error) {
 }
 
 type UploadStreamArgs struct {
-	Out         io.Writer
-	In          io.Reader
-	Name        string
-	Description string
-	Parents     []string
-	Mime        string
-	Share       bool
-	ChunkSize   int64
-	Progress    io.Writer
-	Timeout     time.Duration
+	Out          io.Writer
+	In           io.Reader
+	Name         string
+	Description  string
+	Parents      []string
+	Mime         string
+	Share        bool
+	Verify       bool
+	Encrypt      bool
+	EncryptNames bool
+	ChunkSize    int64
+	Progress     io.Writer
+	Timeout      time.Duration
 }
 
 func (self *Drive) UploadStream(args UploadStreamArgs) error {
+	parents, err := self.resolveIds(args.Parents)
+	if err != nil {
+		return err
+	}
+	args.Parents = parents
+
 	if args.ChunkSize > intMax()-1 {
 		return fmt.Errorf("Chunk size is to big, max chunk size for this computer is %d", intMax()-1)
 	}
@@ -227,21 +362,40 @@ func (self *Drive) UploadStream(args UploadStreamArgs) error {
 	}
 
 	// Set parent folders
-	dstFile.Parents = args.Parents
+	dstFile.Parents = self.defaultParents(args.Parents)
 
-	// Chunk size option
-	chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
-
-	// Wrap file in progress reader
-	progressReader := utils.GetProgressReader(args.In, args.Progress, 0)
-
-	// Wrap reader in timeout reader
-	reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.Timeout)
+	fields := []googleapi.Field{"id", "name", "size", "md5Checksum", "sha1Checksum", "sha256Checksum", "webContentLink"}
 
 	fmt.Fprintf(args.Out, "Uploading %s\n", dstFile.Name)
 	started := time.Now()
 
-	f, err := self.service.Files.Create(dstFile).Fields("id", "name", "size", "webContentLink").Context(ctx).Media(reader, chunkSize).Do()
+	checksums := newVerifyChecksums(args.Verify)
+
+	var f *drive.File
+
+	if args.Encrypt {
+		f, err = self.uploadEncrypted(encryptedUploadArgs{
+			src:         args.In,
+			file:        dstFile,
+			fields:      fields,
+			encryptName: args.EncryptNames,
+			chunkSize:   args.ChunkSize,
+			progress:    args.Progress,
+			timeout:     args.Timeout,
+			checksums:   checksums,
+		})
+	} else {
+		// Chunk size option
+		chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
+
+		// Wrap file in progress reader
+		progressReader := utils.GetProgressReader(checksumReader(args.In, checksums), args.Progress, 0)
+
+		// Wrap reader in timeout reader
+		reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.Timeout)
+
+		f, err = self.service.Files.Create(dstFile).SupportsAllDrives(true).Fields(fields...).Context(ctx).Media(reader, chunkSize).Do()
+	}
 	if err != nil {
 		if isTimeoutError(err) {
 			return fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
@@ -249,6 +403,10 @@ func (self *Drive) UploadStream(args UploadStreamArgs) error {
 		return fmt.Errorf("Failed to upload file: %s", err)
 	}
 
+	if err := verifyUpload(checksums, f); err != nil {
+		return err
+	}
+
 	// Calculate average upload rate
 	rate := calcRate(f.Size, started, time.Now())
 
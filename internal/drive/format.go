@@ -0,0 +1,60 @@
+package drive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Parses a docker style --format template. Besides the builtin functions the
+// template can use size, bytes, datetime, path, join and json. No template
+// is returned for an empty format
+func (self *Drive) newFormatTemplate(format string) (*template.Template, error) {
+	if format == "" {
+		return nil, nil
+	}
+
+	pathfinder := self.newPathfinder()
+
+	funcs := template.FuncMap{
+		"size": func(bytes int64) string {
+			return formatSize(bytes, false)
+		},
+		"bytes": func(bytes int64) string {
+			return formatSize(bytes, true)
+		},
+		"datetime": formatDatetime,
+		"path": func(f *drive.File) (string, error) {
+			if f == nil {
+				return "", nil
+			}
+			return pathfinder.absPath(f)
+		},
+		"join": strings.Join,
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+
+	tmpl, err := template.New("format").Funcs(funcs).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid format: %s", err)
+	}
+
+	return tmpl, nil
+}
+
+// Executes the template for one item followed by a newline
+func printFormatted(out io.Writer, tmpl *template.Template, item interface{}) error {
+	if err := tmpl.Execute(out, item); err != nil {
+		return fmt.Errorf("Failed to format output: %s", err)
+	}
+
+	_, err := fmt.Fprintln(out)
+	return err
+}
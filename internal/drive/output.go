@@ -0,0 +1,204 @@
+package drive
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type OutputFormat int
+
+const (
+	TableOutput OutputFormat = iota
+	JsonOutput
+	NdjsonOutput
+	CsvOutput
+)
+
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch s {
+	case "", "table":
+		return TableOutput, nil
+	case "json":
+		return JsonOutput, nil
+	case "ndjson":
+		return NdjsonOutput, nil
+	case "csv":
+		return CsvOutput, nil
+	}
+
+	return TableOutput, fmt.Errorf("Unknown output format '%s', must be one of json, ndjson, table or csv", s)
+}
+
+// Record with the fields in the order they should be printed
+type outputRecord []outputField
+
+type outputField struct {
+	key   string
+	value interface{}
+}
+
+func (self outputRecord) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString("{")
+
+	for i, field := range self {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.Write(key)
+		buffer.WriteString(":")
+		buffer.Write(value)
+	}
+
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// Writes records in one of the structured output formats. Records are written
+// as they arrive, so the writer can be used for listings that never end
+type recordWriter struct {
+	out        io.Writer
+	format     OutputFormat
+	skipHeader bool
+	csv        *csv.Writer
+	count      int
+}
+
+func newRecordWriter(out io.Writer, format OutputFormat, skipHeader bool) *recordWriter {
+	return &recordWriter{
+		out:        out,
+		format:     format,
+		skipHeader: skipHeader,
+		csv:        csv.NewWriter(out),
+	}
+}
+
+func (self *recordWriter) write(record outputRecord) error {
+	defer func() { self.count++ }()
+
+	switch self.format {
+	case JsonOutput:
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		prefix := ",\n  "
+		if self.count == 0 {
+			prefix = "[\n  "
+		}
+
+		_, err = fmt.Fprintf(self.out, "%s%s", prefix, data)
+		return err
+
+	case NdjsonOutput:
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(self.out, "%s\n", data)
+		return err
+
+	case CsvOutput:
+		if self.count == 0 && !self.skipHeader {
+			var header []string
+			for _, field := range record {
+				header = append(header, field.key)
+			}
+			self.csv.Write(header)
+		}
+
+		var values []string
+		for _, field := range record {
+			values = append(values, formatCsvValue(field.value))
+		}
+		self.csv.Write(values)
+		self.csv.Flush()
+		return self.csv.Error()
+	}
+
+	return fmt.Errorf("Output format does not support records")
+}
+
+// Finishes the output, must be called after the last record is written
+func (self *recordWriter) close() error {
+	if self.format != JsonOutput {
+		return nil
+	}
+
+	if self.count == 0 {
+		_, err := fmt.Fprintln(self.out, "[]")
+		return err
+	}
+
+	_, err := fmt.Fprintln(self.out, "\n]")
+	return err
+}
+
+func writeRecords(out io.Writer, format OutputFormat, skipHeader bool, records []outputRecord) error {
+	w := newRecordWriter(out, format, skipHeader)
+
+	for _, record := range records {
+		if err := w.write(record); err != nil {
+			return err
+		}
+	}
+
+	return w.close()
+}
+
+// Writes a single record, which is written as an object rather than a list in json
+func writeRecord(out io.Writer, format OutputFormat, record outputRecord) error {
+	if format != JsonOutput {
+		return writeRecords(out, format, false, []outputRecord{record})
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+// Lists are always written as lists, also when they are empty
+func nonNilList(a []string) []string {
+	if a == nil {
+		return []string{}
+	}
+	return a
+}
+
+func formatCsvValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case []string:
+		return strings.Join(v, ",")
+	case nil:
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
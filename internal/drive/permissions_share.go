@@ -3,6 +3,7 @@ package drive
 import (
 	"fmt"
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"io"
 	"text/tabwriter"
 )
@@ -18,6 +19,10 @@ type ShareArgs struct {
 }
 
 func (self *Drive) Share(args ShareArgs) error {
+	if err := self.resolvePaths(&args.FileId); err != nil {
+		return err
+	}
+
 	permission := &drive.Permission{
 		AllowFileDiscovery: args.Discoverable,
 		Role:               args.Role,
@@ -26,7 +31,7 @@ func (self *Drive) Share(args ShareArgs) error {
 		Domain:             args.Domain,
 	}
 
-	_, err := self.service.Permissions.Create(args.FileId, permission).Do()
+	_, err := self.service.Permissions.Create(args.FileId, permission).SupportsAllDrives(true).Do()
 	if err != nil {
 		return fmt.Errorf("Failed to share file: %s", err)
 	}
@@ -42,7 +47,11 @@ type RevokePermissionArgs struct {
 }
 
 func (self *Drive) RevokePermission(args RevokePermissionArgs) error {
-	err := self.service.Permissions.Delete(args.FileId, args.PermissionId).Do()
+	if err := self.resolvePaths(&args.FileId); err != nil {
+		return err
+	}
+
+	err := self.service.Permissions.Delete(args.FileId, args.PermissionId).SupportsAllDrives(true).Do()
 	if err != nil {
 		return fmt.Errorf("Failed to revoke permission: %s", err)
 	}
@@ -54,17 +63,45 @@ func (self *Drive) RevokePermission(args RevokePermissionArgs) error {
 type ListPermissionsArgs struct {
 	Out    io.Writer
 	FileId string
+	Output OutputFormat
+	Format string
 }
 
 func (self *Drive) ListPermissions(args ListPermissionsArgs) error {
-	permList, err := self.service.Permissions.List(args.FileId).Fields("permissions(id,role,type,domain,emailAddress,allowFileDiscovery)").Do()
+	if err := self.resolvePaths(&args.FileId); err != nil {
+		return err
+	}
+
+	tmpl, err := self.newFormatTemplate(args.Format)
+	if err != nil {
+		return err
+	}
+
+	fields := []googleapi.Field{"permissions(id,role,type,domain,emailAddress,allowFileDiscovery)"}
+
+	// Any field may be used in the format template
+	if tmpl != nil {
+		fields = []googleapi.Field{"permissions(*)"}
+	}
+
+	permList, err := self.service.Permissions.List(args.FileId).SupportsAllDrives(true).Fields(fields...).Do()
 	if err != nil {
 		return fmt.Errorf("Failed to list permissions: %s", err)
 	}
 
+	if tmpl != nil {
+		for _, p := range permList.Permissions {
+			if err := printFormatted(args.Out, tmpl, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	printPermissions(printPermissionsArgs{
 		out:         args.Out,
 		permissions: permList.Permissions,
+		output:      args.Output,
 	})
 	return nil
 }
@@ -75,7 +112,7 @@ func (self *Drive) shareAnyoneReader(fileId string) error {
 		Type: "anyone",
 	}
 
-	_, err := self.service.Permissions.Create(fileId, permission).Do()
+	_, err := self.service.Permissions.Create(fileId, permission).SupportsAllDrives(true).Do()
 	if err != nil {
 		return fmt.Errorf("Failed to share file: %s", err)
 	}
@@ -86,9 +123,26 @@ func (self *Drive) shareAnyoneReader(fileId string) error {
 type printPermissionsArgs struct {
 	out         io.Writer
 	permissions []*drive.Permission
+	output      OutputFormat
 }
 
 func printPermissions(args printPermissionsArgs) {
+	if args.output != TableOutput {
+		var records []outputRecord
+		for _, p := range args.permissions {
+			records = append(records, outputRecord{
+				{"id", p.Id},
+				{"type", p.Type},
+				{"role", p.Role},
+				{"emailAddress", p.EmailAddress},
+				{"domain", p.Domain},
+				{"allowFileDiscovery", p.AllowFileDiscovery},
+			})
+		}
+		writeRecords(args.out, args.output, false, records)
+		return
+	}
+
 	w := new(tabwriter.Writer)
 	w.Init(args.out, 0, 0, 3, ' ', 0)
 
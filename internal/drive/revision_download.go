@@ -1,12 +1,12 @@
 package drive
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"path/filepath"
 	"time"
-
-	"github.com/imzza/gdrive/internal/utils"
 )
 
 type DownloadRevisionArgs struct {
@@ -21,9 +21,7 @@ type DownloadRevisionArgs struct {
 }
 
 func (self *Drive) DownloadRevision(args DownloadRevisionArgs) (err error) {
-	getRev := self.service.Revisions.Get(args.FileId, args.RevisionId)
-
-	rev, err := getRev.Fields("originalFilename").Do()
+	rev, err := self.service.Revisions.Get(args.FileId, args.RevisionId).Fields("originalFilename", "md5Checksum", "size").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get file: %s", err)
 	}
@@ -32,20 +30,6 @@ func (self *Drive) DownloadRevision(args DownloadRevisionArgs) (err error) {
 		return fmt.Errorf("Download is not supported for this file type")
 	}
 
-	// Get timeout reader wrapper and context
-	timeoutReaderWrapper, ctx := utils.GetTimeoutReaderWrapperContext(args.Timeout)
-
-	res, err := getRev.Context(ctx).Download()
-	if err != nil {
-		if isTimeoutError(err) {
-			return fmt.Errorf("Failed to download file: timeout, no data was transferred for %v", args.Timeout)
-		}
-		return fmt.Errorf("Failed to download file: %s", err)
-	}
-
-	// Close body on function exit
-	defer res.Body.Close()
-
 	// Discard other output if file is written to stdout
 	out := args.Out
 	if args.Stdout {
@@ -58,13 +42,19 @@ func (self *Drive) DownloadRevision(args DownloadRevisionArgs) (err error) {
 	fmt.Fprintf(out, "Downloading %s -> %s\n", rev.OriginalFilename, fpath)
 
 	bytes, rate, err := self.saveFile(saveFileArgs{
-		out:           args.Out,
-		body:          timeoutReaderWrapper(res.Body),
-		contentLength: res.ContentLength,
-		fpath:         fpath,
-		force:         args.Force,
-		stdout:        args.Stdout,
-		progress:      args.Progress,
+		out: args.Out,
+		download: func(ctx context.Context, offset int64) (*http.Response, error) {
+			call := self.service.Revisions.Get(args.FileId, args.RevisionId).Context(ctx)
+			setRangeHeader(call.Header(), offset)
+			return call.Download()
+		},
+		md5:      rev.Md5Checksum,
+		size:     rev.Size,
+		fpath:    fpath,
+		force:    args.Force,
+		stdout:   args.Stdout,
+		progress: args.Progress,
+		timeout:  args.Timeout,
 	})
 
 	if err != nil {
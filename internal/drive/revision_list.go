@@ -3,6 +3,7 @@ package drive
 import (
 	"fmt"
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"io"
 	"text/tabwriter"
 )
@@ -13,20 +14,44 @@ type ListRevisionsArgs struct {
 	NameWidth   int64
 	SkipHeader  bool
 	SizeInBytes bool
+	Output      OutputFormat
+	Format      string
 }
 
 func (self *Drive) ListRevisions(args ListRevisionsArgs) (err error) {
-	revList, err := self.service.Revisions.List(args.Id).Fields("revisions(id,keepForever,size,modifiedTime,originalFilename)").Do()
+	tmpl, err := self.newFormatTemplate(args.Format)
+	if err != nil {
+		return err
+	}
+
+	fields := []googleapi.Field{"revisions(id,keepForever,size,modifiedTime,originalFilename)"}
+
+	// Any field may be used in the format template
+	if tmpl != nil {
+		fields = []googleapi.Field{"revisions(*)"}
+	}
+
+	revList, err := self.service.Revisions.List(args.Id).Fields(fields...).Do()
 	if err != nil {
 		return fmt.Errorf("Failed listing revisions: %s", err)
 	}
 
+	if tmpl != nil {
+		for _, rev := range revList.Revisions {
+			if err := printFormatted(args.Out, tmpl, rev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	PrintRevisionList(PrintRevisionListArgs{
 		Out:         args.Out,
 		Revisions:   revList.Revisions,
 		NameWidth:   int(args.NameWidth),
 		SkipHeader:  args.SkipHeader,
 		SizeInBytes: args.SizeInBytes,
+		Output:      args.Output,
 	})
 
 	return
@@ -38,9 +63,25 @@ type PrintRevisionListArgs struct {
 	NameWidth   int
 	SkipHeader  bool
 	SizeInBytes bool
+	Output      OutputFormat
 }
 
 func PrintRevisionList(args PrintRevisionListArgs) {
+	if args.Output != TableOutput {
+		var records []outputRecord
+		for _, rev := range args.Revisions {
+			records = append(records, outputRecord{
+				{"id", rev.Id},
+				{"originalFilename", rev.OriginalFilename},
+				{"size", rev.Size},
+				{"modifiedTime", rev.ModifiedTime},
+				{"keepForever", rev.KeepForever},
+			})
+		}
+		writeRecords(args.Out, args.Output, args.SkipHeader, records)
+		return
+	}
+
 	w := new(tabwriter.Writer)
 	w.Init(args.Out, 0, 0, 3, ' ', 0)
 
@@ -0,0 +1,292 @@
+package drive
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const DefaultServeAddr = "localhost:8080"
+const DefaultServeCacheTtl = 60
+
+var serveFields = []googleapi.Field{"id", "name", "mimeType", "size", "md5Checksum", "modifiedTime", "createdTime", "parents"}
+
+// Returns the name a file is served under, and false if the file is not served
+type servedNameFunc func(f *drive.File) (string, bool)
+
+// The files below a served folder, looked up by path. Directory
+// listings are cached for the given time, so that clients walking
+// the tree do not cause a request to drive for every file
+type remoteTree struct {
+	drive      *Drive
+	root       *drive.File
+	ttl        time.Duration
+	servedName servedNameFunc
+	mutex      sync.Mutex
+	listings   map[string]*cachedListing
+}
+
+type cachedListing struct {
+	files   []*servedFile
+	fetched time.Time
+}
+
+type servedFile struct {
+	name string
+	file *drive.File
+}
+
+// Without a root id the root of the drive is served
+func (self *Drive) newRemoteTree(rootId string, ttl time.Duration, servedName servedNameFunc) (*remoteTree, error) {
+	if rootId == "" {
+		rootId = "root"
+		if self.driveId != "" {
+			rootId = self.driveId
+		}
+	}
+
+	rootId, err := self.resolveId(rootId)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := self.service.Files.Get(rootId).SupportsAllDrives(true).Fields(serveFields...).Do()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get file: %s", err)
+	}
+
+	if !isDir(root) {
+		return nil, fmt.Errorf("'%s' is not a directory", root.Name)
+	}
+
+	return &remoteTree{
+		drive:      self,
+		root:       root,
+		ttl:        ttl,
+		servedName: servedName,
+		listings:   map[string]*cachedListing{},
+	}, nil
+}
+
+// Returns the file at the given path below the root
+func (self *remoteTree) lookup(p string) (*drive.File, error) {
+	f := self.root
+
+	for _, name := range splitRemotePath(p) {
+		if !isDir(f) {
+			return nil, &os.PathError{Op: "lookup", Path: p, Err: os.ErrNotExist}
+		}
+
+		child, err := self.child(f.Id, name)
+		if err != nil {
+			return nil, err
+		}
+
+		f = child
+		if f == nil {
+			return nil, &os.PathError{Op: "lookup", Path: p, Err: os.ErrNotExist}
+		}
+	}
+
+	return f, nil
+}
+
+// Returns the directory that contains the file at the given path and the name of the file
+func (self *remoteTree) lookupParent(p string) (*drive.File, string, error) {
+	names := splitRemotePath(p)
+	if len(names) == 0 {
+		return nil, "", &os.PathError{Op: "lookup", Path: p, Err: os.ErrInvalid}
+	}
+
+	parent, err := self.lookup(strings.Join(names[:len(names)-1], "/"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !isDir(parent) {
+		return nil, "", &os.PathError{Op: "lookup", Path: p, Err: os.ErrNotExist}
+	}
+
+	return parent, names[len(names)-1], nil
+}
+
+// Returns the served files of the directory. When several files have the
+// same name only the oldest one is served, as the others can not be told apart by path
+func (self *remoteTree) list(dirId string) ([]*servedFile, error) {
+	self.mutex.Lock()
+	listing, ok := self.listings[dirId]
+	self.mutex.Unlock()
+
+	if ok && time.Since(listing.fetched) < self.ttl {
+		return listing.files, nil
+	}
+
+	listArgs := listAllFilesArgs{
+		query:     fmt.Sprintf("'%s' in parents and trashed = false", dirId),
+		fields:    []googleapi.Field{"nextPageToken", googleapi.Field(fmt.Sprintf("files(%s)", googleapi.CombineFields(serveFields)))},
+		sortOrder: "folder,name,createdTime",
+	}
+	files, err := self.drive.listAllFiles(listArgs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed listing files: %s", err)
+	}
+
+	var served []*servedFile
+	seen := map[string]bool{}
+
+	for _, f := range files {
+		name, ok := self.servedName(f)
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		served = append(served, &servedFile{name, f})
+	}
+
+	self.mutex.Lock()
+	self.listings[dirId] = &cachedListing{served, time.Now()}
+	self.mutex.Unlock()
+
+	return served, nil
+}
+
+// Returns the served file with the given name in the directory, or nil if there is none
+func (self *remoteTree) child(dirId, name string) (*drive.File, error) {
+	files, err := self.list(dirId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		if f.name == name {
+			return f.file, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Drops the cached listings of directories whose content changed
+func (self *remoteTree) invalidate(dirIds ...string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for _, id := range dirIds {
+		delete(self.listings, id)
+	}
+}
+
+// Requires the given credentials, formatted as user:password, from
+// every client. Without credentials the handler is returned as is
+func basicAuthHandler(handler http.Handler, credentials string) http.Handler {
+	if credentials == "" {
+		return handler
+	}
+
+	wantUser, wantPassword, _ := strings.Cut(credentials, ":")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		userOk := subtle.ConstantTimeCompare([]byte(user), []byte(wantUser)) == 1
+		passwordOk := subtle.ConstantTimeCompare([]byte(password), []byte(wantPassword)) == 1
+
+		if !ok || !userOk || !passwordOk {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gdrive"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func parseModifiedTime(f *drive.File) time.Time {
+	t, _ := time.Parse(time.RFC3339, f.ModifiedTime)
+	return t
+}
+
+// Reads the content of a file from the current offset. A new download is
+// started when reading from another offset, so that range requests only
+// transfer the requested part of the file
+type remoteFileReader struct {
+	ctx        context.Context
+	drive      *Drive
+	file       *drive.File
+	offset     int64
+	body       io.ReadCloser
+	bodyOffset int64
+}
+
+func (self *remoteFileReader) Read(p []byte) (int, error) {
+	if isDir(self.file) {
+		return 0, fmt.Errorf("'%s' is a directory", self.file.Name)
+	}
+
+	if self.offset >= self.file.Size {
+		return 0, io.EOF
+	}
+
+	if self.body == nil || self.bodyOffset != self.offset {
+		self.closeBody()
+
+		call := self.drive.service.Files.Get(self.file.Id).SupportsAllDrives(true).Context(self.ctx)
+		setRangeHeader(call.Header(), self.offset)
+
+		res, err := call.Download()
+		if err != nil {
+			return 0, fmt.Errorf("Failed to download file: %s", err)
+		}
+
+		self.body = res.Body
+		self.bodyOffset = self.offset
+
+		// Skip content before the offset if the range header was ignored
+		if self.offset > 0 && res.StatusCode != http.StatusPartialContent {
+			if _, err := io.CopyN(io.Discard, self.body, self.offset); err != nil {
+				return 0, fmt.Errorf("Failed to download file: %s", err)
+			}
+		}
+	}
+
+	n, err := self.body.Read(p)
+	self.offset += int64(n)
+	self.bodyOffset += int64(n)
+	return n, err
+}
+
+func (self *remoteFileReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += self.offset
+	case io.SeekEnd:
+		offset += self.file.Size
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("Invalid offset %d", offset)
+	}
+
+	self.offset = offset
+	return offset, nil
+}
+
+func (self *remoteFileReader) Close() error {
+	self.closeBody()
+	return nil
+}
+
+func (self *remoteFileReader) closeBody() {
+	if self.body != nil {
+		self.body.Close()
+		self.body = nil
+	}
+}
@@ -0,0 +1,227 @@
+package drive
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+type ServeHttpArgs struct {
+	Out         io.Writer
+	RootId      string
+	Addr        string
+	Credentials string
+	CacheTtl    time.Duration
+}
+
+func (self *Drive) ServeHttp(args ServeHttpArgs) error {
+	tree, err := self.newRemoteTree(args.RootId, args.CacheTtl, httpName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Serving '%s' over HTTP at http://%s/\n", tree.root.Name, args.Addr)
+	return http.ListenAndServe(args.Addr, basicAuthHandler(&httpServer{tree, args}, args.Credentials))
+}
+
+// Directories and binary files are served as they are, google
+// documents are exported with their default export mime type
+func httpName(f *drive.File) (string, bool) {
+	if isDir(f) || isBinary(f) {
+		return f.Name, true
+	}
+
+	exportMime, ok := DefaultExportMime[f.MimeType]
+	if !ok {
+		return "", false
+	}
+
+	return getExportFilename(f.Name, exportMime), true
+}
+
+// Returns the content type a file is served with
+func servedMime(f *drive.File) string {
+	if exportMime, ok := DefaultExportMime[f.MimeType]; ok {
+		return exportMime
+	}
+	return f.MimeType
+}
+
+// Serves the files of a drive folder read only
+type httpServer struct {
+	tree *remoteTree
+	args ServeHttpArgs
+}
+
+func (self *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		logServeRequest(self.args.Out, r, fmt.Errorf("Method not allowed"))
+		return
+	}
+
+	err := self.serve(w, r)
+	logServeRequest(self.args.Out, r, err)
+}
+
+func (self *httpServer) serve(w http.ResponseWriter, r *http.Request) error {
+	p := path.Clean("/" + r.URL.Path)
+
+	f, err := self.tree.lookup(p)
+	if err != nil {
+		http.Error(w, "File not found", statusForError(err))
+		return err
+	}
+
+	if isDir(f) {
+		return self.serveDirectory(w, r, p, f)
+	}
+
+	if isBinary(f) {
+		return self.serveFile(w, r, f)
+	}
+
+	return self.serveExport(w, r, f)
+}
+
+var directoryTemplate = template.Must(template.New("directory").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Path}}</title>
+</head>
+<body>
+<h1>{{.Path}}</h1>
+<table>
+<tr><th align="left">Name</th><th align="right">Size</th><th align="left">Modified</th></tr>
+{{if ne .Path "/"}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{end}}{{range .Files}}<tr><td><a href="{{.Href}}">{{.Name}}</a></td><td align="right">{{.Size}}</td><td>{{.Modified}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type directoryEntry struct {
+	Name     string
+	Href     string
+	Size     string
+	Modified string
+}
+
+func (self *httpServer) serveDirectory(w http.ResponseWriter, r *http.Request, p string, dir *drive.File) error {
+	// Relative links in the listing only work below a trailing slash
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		u := *r.URL
+		u.Path += "/"
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return nil
+	}
+
+	files, err := self.tree.list(dir.Id)
+	if err != nil {
+		http.Error(w, "Failed listing files", http.StatusBadGateway)
+		return err
+	}
+
+	if wantsJson(r) {
+		var records []outputRecord
+		for _, f := range files {
+			records = append(records, outputRecord{
+				{"name", f.name},
+				{"path", path.Join(p, f.name)},
+				{"id", f.file.Id},
+				{"type", filetype(f.file)},
+				{"mimeType", servedMime(f.file)},
+				{"size", f.file.Size},
+				{"md5Checksum", f.file.Md5Checksum},
+				{"modifiedTime", f.file.ModifiedTime},
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodHead {
+			return nil
+		}
+		return writeRecords(w, JsonOutput, false, records)
+	}
+
+	var entries []directoryEntry
+	for _, f := range files {
+		// The ./ prefix keeps names with a colon from being taken for a url scheme
+		entry := directoryEntry{
+			Name:     f.name,
+			Href:     "./" + url.PathEscape(f.name),
+			Size:     "-",
+			Modified: formatDatetime(f.file.ModifiedTime),
+		}
+
+		if isDir(f.file) {
+			entry.Name += "/"
+			entry.Href += "/"
+		} else if isBinary(f.file) {
+			entry.Size = formatSize(f.file.Size, false)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	return directoryTemplate.Execute(w, map[string]interface{}{
+		"Path":  p,
+		"Files": entries,
+	})
+}
+
+func wantsJson(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// Serves the content of a binary file, range and conditional requests are handled by ServeContent
+func (self *httpServer) serveFile(w http.ResponseWriter, r *http.Request, f *drive.File) error {
+	w.Header().Set("Content-Type", f.MimeType)
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, f.Md5Checksum))
+
+	reader := &remoteFileReader{ctx: r.Context(), drive: self.tree.drive, file: f}
+	defer reader.Close()
+
+	http.ServeContent(w, r, f.Name, parseModifiedTime(f), reader)
+	return nil
+}
+
+// Exports a google document while it is sent, the size of an export is
+// not known up front so ranges are not supported
+func (self *httpServer) serveExport(w http.ResponseWriter, r *http.Request, f *drive.File) error {
+	exportMime := servedMime(f)
+
+	w.Header().Set("Content-Type", exportMime)
+	w.Header().Set("Last-Modified", parseModifiedTime(f).UTC().Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "none")
+
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	res, err := self.tree.drive.service.Files.Export(f.Id, exportMime).Context(r.Context()).Download()
+	if err != nil {
+		http.Error(w, "Failed to export file", http.StatusBadGateway)
+		return fmt.Errorf("Failed to export file: %s", err)
+	}
+
+	// Close body on function exit
+	defer res.Body.Close()
+
+	_, err = io.Copy(w, res.Body)
+	return err
+}
@@ -0,0 +1,484 @@
+package drive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/webdav"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+type ServeWebdavArgs struct {
+	Out         io.Writer
+	RootId      string
+	Addr        string
+	Credentials string
+	CacheTtl    time.Duration
+	ChunkSize   int64
+	Permanent   bool
+}
+
+func (self *Drive) ServeWebdav(args ServeWebdavArgs) error {
+	if args.ChunkSize > intMax()-1 {
+		return fmt.Errorf("Chunk size is to big, max chunk size for this computer is %d", intMax()-1)
+	}
+
+	tree, err := self.newRemoteTree(args.RootId, args.CacheTtl, webdavName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Serving '%s' over WebDAV at http://%s/\n", tree.root.Name, args.Addr)
+	return http.ListenAndServe(args.Addr, basicAuthHandler(newWebdavServer(tree, args), args.Credentials))
+}
+
+func newWebdavServer(tree *remoteTree, args ServeWebdavArgs) *webdavServer {
+	fs := &webdavFileSystem{tree: tree, args: args}
+
+	return &webdavServer{
+		fs: fs,
+		handler: &webdav.Handler{
+			FileSystem: fs,
+			LockSystem: webdav.NewMemLS(),
+			Logger: func(r *http.Request, err error) {
+				logServeRequest(args.Out, r, err)
+			},
+		},
+	}
+}
+
+func logServeRequest(out io.Writer, r *http.Request, err error) {
+	if err != nil {
+		fmt.Fprintf(out, "%s %s: %s\n", r.Method, r.URL.Path, err)
+		return
+	}
+	fmt.Fprintf(out, "%s %s\n", r.Method, r.URL.Path)
+}
+
+// Copies are made on the server side by drive instead of
+// downloading and uploading the content like the webdav package does
+type webdavServer struct {
+	fs      *webdavFileSystem
+	handler *webdav.Handler
+}
+
+func (self *webdavServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "COPY" {
+		self.handler.ServeHTTP(w, r)
+		return
+	}
+
+	status, err := self.copy(r)
+	logServeRequest(self.fs.args.Out, r, err)
+
+	w.WriteHeader(status)
+	if err != nil && status != http.StatusNoContent {
+		fmt.Fprintln(w, err)
+	}
+}
+
+func (self *webdavServer) copy(r *http.Request) (int, error) {
+	dest, err := url.Parse(r.Header.Get("Destination"))
+	if err != nil || dest.Path == "" {
+		return http.StatusBadRequest, fmt.Errorf("Invalid destination")
+	}
+
+	if dest.Host != "" && dest.Host != r.Host {
+		return http.StatusBadGateway, fmt.Errorf("Destination is on another server")
+	}
+
+	src := path.Clean("/" + r.URL.Path)
+	dst := path.Clean("/" + dest.Path)
+	if src == dst || isBelowPath(dst, src) || isBelowPath(src, dst) {
+		return http.StatusForbidden, fmt.Errorf("Can not copy '%s' to '%s'", src, dst)
+	}
+
+	f, err := self.fs.tree.lookup(src)
+	if err != nil {
+		return statusForError(err), err
+	}
+
+	parent, name, err := self.fs.tree.lookupParent(dst)
+	if err != nil {
+		return http.StatusConflict, err
+	}
+
+	existing, err := self.fs.tree.child(parent.Id, name)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	status := http.StatusCreated
+	if existing != nil {
+		if r.Header.Get("Overwrite") == "F" {
+			return http.StatusPreconditionFailed, fmt.Errorf("'%s' already exists", dst)
+		}
+
+		if err := self.fs.tree.drive.deleteFile(existing.Id, self.fs.args.Permanent); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		status = http.StatusNoContent
+	}
+
+	defer self.fs.tree.invalidate(parent.Id)
+
+	// Copy under the name of the destination
+	renamed := *f
+	renamed.Name = name
+
+	if !isDir(f) {
+		_, err = self.fs.tree.drive.copyFile(&renamed, parent.Id, true, 0)
+	} else if r.Header.Get("Depth") == "0" {
+		_, err = self.fs.tree.drive.mkdir(MkdirArgs{Name: name, Parents: []string{parent.Id}})
+	} else {
+		tree := &copyTree{
+			args:    CopyArgs{Out: io.Discard, Preserve: true},
+			created: map[string]bool{},
+		}
+		err = self.fs.tree.drive.copyDirectory(&renamed, parent.Id, name, tree)
+	}
+
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return status, nil
+}
+
+func isBelowPath(p, dir string) bool {
+	return strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+}
+
+func statusForError(err error) int {
+	if os.IsNotExist(err) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// Only directories and files with binary content are served,
+// google documents have no content that could be read or written
+func webdavName(f *drive.File) (string, bool) {
+	return f.Name, isDir(f) || isBinary(f)
+}
+
+// Maps the webdav file system onto a drive folder
+type webdavFileSystem struct {
+	tree *remoteTree
+	args ServeWebdavArgs
+}
+
+func (self *webdavFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	parent, base, err := self.tree.lookupParent(name)
+	if err != nil {
+		return err
+	}
+
+	existing, err := self.tree.child(parent.Id, base)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+
+	defer self.tree.invalidate(parent.Id)
+
+	_, err = self.tree.drive.mkdir(MkdirArgs{Name: base, Parents: []string{parent.Id}})
+	return err
+}
+
+func (self *webdavFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return self.create(ctx, name, flag)
+	}
+
+	f, err := self.tree.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webdavFile{
+		remoteFileReader: remoteFileReader{ctx: ctx, drive: self.tree.drive, file: f},
+		tree:             self.tree,
+	}, nil
+}
+
+func (self *webdavFileSystem) create(ctx context.Context, name string, flag int) (webdav.File, error) {
+	parent, base, err := self.tree.lookupParent(name)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := self.tree.child(parent.Id, base)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil && flag&os.O_EXCL != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	}
+
+	if existing != nil && isDir(existing) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: fmt.Errorf("Is a directory")}
+	}
+
+	if existing == nil && flag&os.O_CREATE == 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	pr, pw := io.Pipe()
+
+	upload := &webdavUpload{
+		fs:      self,
+		ctx:     ctx,
+		name:    base,
+		parent:  parent,
+		writer:  pw,
+		done:    make(chan struct{}),
+		started: time.Now(),
+	}
+
+	go upload.run(ctx, pr, existing)
+
+	return upload, nil
+}
+
+func (self *webdavFileSystem) RemoveAll(ctx context.Context, name string) error {
+	if len(splitRemotePath(name)) == 0 {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+	}
+
+	parent, base, err := self.tree.lookupParent(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	f, err := self.tree.child(parent.Id, base)
+	if f == nil || err != nil {
+		return err
+	}
+
+	defer self.tree.invalidate(parent.Id, f.Id)
+
+	return self.tree.drive.deleteFile(f.Id, self.args.Permanent)
+}
+
+func (self *webdavFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	if len(splitRemotePath(oldName)) == 0 {
+		return &os.PathError{Op: "rename", Path: oldName, Err: os.ErrPermission}
+	}
+
+	oldParent, _, err := self.tree.lookupParent(oldName)
+	if err != nil {
+		return err
+	}
+
+	f, err := self.tree.lookup(oldName)
+	if err != nil {
+		return err
+	}
+
+	newParent, newBase, err := self.tree.lookupParent(newName)
+	if err != nil {
+		return err
+	}
+
+	defer self.tree.invalidate(oldParent.Id, newParent.Id)
+
+	call := self.tree.drive.service.Files.Update(f.Id, &drive.File{Name: newBase}).SupportsAllDrives(true)
+	if oldParent.Id != newParent.Id {
+		call = call.AddParents(newParent.Id).RemoveParents(oldParent.Id)
+	}
+
+	if _, err := call.Do(); err != nil {
+		return fmt.Errorf("Failed to move file: %s", err)
+	}
+
+	return nil
+}
+
+func (self *webdavFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	f, err := self.tree.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return remoteFileInfo{f}, nil
+}
+
+// A file or directory opened for reading
+type webdavFile struct {
+	remoteFileReader
+	tree   *remoteTree
+	dirPos int
+}
+
+func (self *webdavFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !isDir(self.file) {
+		return nil, fmt.Errorf("'%s' is not a directory", self.file.Name)
+	}
+
+	files, err := self.tree.list(self.file.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if self.dirPos >= len(files) && count > 0 {
+		return nil, io.EOF
+	}
+
+	files = files[self.dirPos:]
+	if count > 0 && count < len(files) {
+		files = files[:count]
+	}
+	self.dirPos += len(files)
+
+	var infos []os.FileInfo
+	for _, f := range files {
+		infos = append(infos, remoteFileInfo{f.file})
+	}
+
+	return infos, nil
+}
+
+func (self *webdavFile) Stat() (os.FileInfo, error) {
+	return remoteFileInfo{self.file}, nil
+}
+
+func (self *webdavFile) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("'%s' is opened for reading", self.file.Name)
+}
+
+// A file opened for writing. What is written is streamed into an upload
+// that completes when the file is closed. An existing file gets a new revision
+type webdavUpload struct {
+	fs      *webdavFileSystem
+	name    string
+	parent  *drive.File
+	writer  *io.PipeWriter
+	written int64
+	started time.Time
+	done    chan struct{}
+	err     error
+	// Context of the request, the upload is aborted when it is done
+	ctx context.Context
+}
+
+func (self *webdavUpload) run(ctx context.Context, r *io.PipeReader, existing *drive.File) {
+	defer close(self.done)
+
+	chunkSize := googleapi.ChunkSize(int(self.fs.args.ChunkSize))
+
+	var err error
+	if existing != nil {
+		_, err = self.fs.tree.drive.service.Files.Update(existing.Id, &drive.File{}).SupportsAllDrives(true).Fields("id").Context(ctx).Media(r, chunkSize).Do()
+	} else {
+		dstFile := &drive.File{Name: self.name, Parents: []string{self.parent.Id}}
+		_, err = self.fs.tree.drive.service.Files.Create(dstFile).SupportsAllDrives(true).Fields("id").Context(ctx).Media(r, chunkSize).Do()
+	}
+
+	if err != nil {
+		self.err = fmt.Errorf("Failed to upload file: %s", err)
+	}
+
+	// Writes fail from now on
+	r.CloseWithError(self.err)
+}
+
+func (self *webdavUpload) Write(p []byte) (int, error) {
+	n, err := self.writer.Write(p)
+	self.written += int64(n)
+	return n, err
+}
+
+// The handler closes the file also when reading the request body failed, a
+// request that was aborted must not commit what was received so far
+func (self *webdavUpload) Close() error {
+	if err := self.ctx.Err(); err != nil {
+		self.writer.CloseWithError(err)
+	} else {
+		self.writer.Close()
+	}
+	<-self.done
+	self.fs.tree.invalidate(self.parent.Id)
+	return self.err
+}
+
+func (self *webdavUpload) Stat() (os.FileInfo, error) {
+	return remoteFileInfo{&drive.File{
+		Name:         self.name,
+		Size:         self.written,
+		ModifiedTime: self.started.Format(time.RFC3339),
+	}}, nil
+}
+
+func (self *webdavUpload) Read(p []byte) (int, error) {
+	return 0, fmt.Errorf("'%s' is opened for writing", self.name)
+}
+
+func (self *webdavUpload) Seek(offset int64, whence int) (int64, error) {
+	return 0, fmt.Errorf("'%s' is opened for writing", self.name)
+}
+
+func (self *webdavUpload) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, fmt.Errorf("'%s' is not a directory", self.name)
+}
+
+// Describes a drive file as a local file
+type remoteFileInfo struct {
+	file *drive.File
+}
+
+func (self remoteFileInfo) Name() string {
+	return self.file.Name
+}
+
+func (self remoteFileInfo) Size() int64 {
+	return self.file.Size
+}
+
+func (self remoteFileInfo) Mode() os.FileMode {
+	if isDir(self.file) {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (self remoteFileInfo) ModTime() time.Time {
+	return parseModifiedTime(self.file)
+}
+
+func (self remoteFileInfo) IsDir() bool {
+	return isDir(self.file)
+}
+
+func (self remoteFileInfo) Sys() interface{} {
+	return self.file
+}
+
+func (self remoteFileInfo) ETag(ctx context.Context) (string, error) {
+	if self.file.Md5Checksum == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return fmt.Sprintf(`"%s"`, self.file.Md5Checksum), nil
+}
+
+func (self remoteFileInfo) ContentType(ctx context.Context) (string, error) {
+	if self.file.MimeType == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return self.file.MimeType, nil
+}
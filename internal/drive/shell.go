@@ -0,0 +1,546 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+type ShellArgs struct {
+	In        io.Reader
+	Out       io.Writer
+	Progress  io.Writer
+	ChunkSize int64
+	Timeout   time.Duration
+	Sessions  *UploadSessionStore
+}
+
+// Interactive session with a working directory. Paths given to the commands
+// are relative to the working directory unless they start with a slash or
+// are full drive: or shared: paths
+type shell struct {
+	drive *Drive
+	args  ShellArgs
+	// The drive the working directory is in, drive: or shared:<Drive Name>
+	root string
+	// Path of the working directory within the drive, always starting with a slash
+	dir string
+	// Cached directory listings by directory id, used for ls and completion
+	listings map[string][]*drive.File
+}
+
+type shellCommand struct {
+	usage       string
+	description string
+	run         func(self *shell, args []string) error
+	// Commands that change files make the cached listings stale
+	modifies bool
+}
+
+var shellCommands map[string]shellCommand
+
+// The commands are set up in init as some of them refer to the list of commands
+func init() {
+	shellCommands = map[string]shellCommand{
+		"cd":    {"cd [path]", "Change working directory, the root of the drive without a path", (*shell).cd, false},
+		"ls":    {"ls [path]", "List directory", (*shell).ls, false},
+		"pwd":   {"pwd", "Print working directory", (*shell).pwd, false},
+		"get":   {"get <path> [localPath]", "Download file or directory", (*shell).get, false},
+		"put":   {"put <localPath> [path]", "Upload file or directory", (*shell).put, true},
+		"mv":    {"mv <path> <path>", "Move to directory, or rename when the target does not exist", (*shell).mv, true},
+		"rm":    {"rm [-r] [--permanent] <path>", "Move file or directory to the trash", (*shell).rm, true},
+		"mkdir": {"mkdir <path>", "Create directory", (*shell).mkdir, true},
+		"info":  {"info <path>", "Show file info", (*shell).info, false},
+		"share": {"share <path> [email] [role]", "Share with anyone, or with the given email address", (*shell).share, false},
+		"help":  {"help", "Print this help", (*shell).help, false},
+		"exit":  {"exit", "Leave the shell", nil, false},
+	}
+}
+
+func (self *Drive) Shell(args ShellArgs) error {
+	sh := &shell{
+		drive:    self,
+		args:     args,
+		root:     MyDrivePathPrefix,
+		dir:      "/",
+		listings: map[string][]*drive.File{},
+	}
+
+	// Start in the root of the selected shared drive
+	if self.driveId != "" {
+		d, err := self.service.Drives.Get(self.driveId).Fields("name").Do()
+		if err != nil {
+			return fmt.Errorf("Failed to get drive: %s", err)
+		}
+		sh.root = SharedDrivePathPrefix + d.Name
+	}
+
+	reader := newLineReader(args.In, args.Out, sh.complete)
+
+	for {
+		line, err := reader.readLine(fmt.Sprintf("gdrive %s> ", sh.workingDir()))
+		if err == io.EOF {
+			fmt.Fprintln(args.Out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		words, err := splitShellWords(line)
+		if err != nil {
+			fmt.Fprintln(args.Out, err)
+			continue
+		}
+
+		if len(words) == 0 {
+			continue
+		}
+
+		if words[0] == "exit" || words[0] == "quit" {
+			return nil
+		}
+
+		if err := sh.run(words[0], words[1:]); err != nil {
+			fmt.Fprintln(args.Out, err)
+		}
+	}
+}
+
+func (self *shell) run(name string, args []string) error {
+	cmd, ok := shellCommands[name]
+	if !ok || cmd.run == nil {
+		return fmt.Errorf("Unknown command '%s', see help", name)
+	}
+
+	err := cmd.run(self, args)
+
+	if cmd.modifies {
+		self.listings = map[string][]*drive.File{}
+		self.drive.resolver = nil
+	}
+
+	return err
+}
+
+func (self *shell) workingDir() string {
+	return self.root + self.dir
+}
+
+// Returns the full drive path of a path given to a command
+func (self *shell) absPath(p string) string {
+	if isRemotePath(p) {
+		return p
+	}
+
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join(self.dir, p)
+	}
+
+	return self.root + path.Clean("/"+p)
+}
+
+// Splits a full drive path into the drive and the path within the drive
+func splitShellPath(p string) (string, string) {
+	if strings.HasPrefix(p, SharedDrivePathPrefix) {
+		names := splitRemotePath(strings.TrimPrefix(p, SharedDrivePathPrefix))
+		if len(names) == 0 {
+			return SharedDrivePathPrefix, "/"
+		}
+		return SharedDrivePathPrefix + names[0], path.Clean("/" + strings.Join(names[1:], "/"))
+	}
+
+	return MyDrivePathPrefix, path.Clean("/" + strings.TrimPrefix(p, MyDrivePathPrefix))
+}
+
+func (self *shell) stat(p string) (*drive.File, error) {
+	id, err := self.drive.resolveId(self.absPath(p))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := self.drive.service.Files.Get(id).SupportsAllDrives(true).Fields("id", "name", "mimeType", "md5Checksum", "size", "createdTime", "parents").Do()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get file: %s", err)
+	}
+
+	return f, nil
+}
+
+func (self *shell) statDir(p string) (*drive.File, error) {
+	f, err := self.stat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isDir(f) {
+		return nil, fmt.Errorf("'%s' is not a directory", p)
+	}
+
+	return f, nil
+}
+
+func (self *shell) list(dirId string) ([]*drive.File, error) {
+	if files, ok := self.listings[dirId]; ok {
+		return files, nil
+	}
+
+	listArgs := listAllFilesArgs{
+		query:     fmt.Sprintf("'%s' in parents and trashed = false", dirId),
+		fields:    []googleapi.Field{"nextPageToken", "files(id,name,md5Checksum,mimeType,size,createdTime,parents)"},
+		sortOrder: "folder,name",
+	}
+	files, err := self.drive.listAllFiles(listArgs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed listing files: %s", err)
+	}
+
+	self.listings[dirId] = files
+	return files, nil
+}
+
+func (self *shell) cd(args []string) error {
+	target := "/"
+	if len(args) > 0 {
+		target = args[0]
+	}
+
+	if _, err := self.statDir(target); err != nil {
+		return err
+	}
+
+	self.root, self.dir = splitShellPath(self.absPath(target))
+	return nil
+}
+
+func (self *shell) ls(args []string) error {
+	target := "."
+	if len(args) > 0 {
+		target = args[0]
+	}
+
+	f, err := self.stat(target)
+	if err != nil {
+		return err
+	}
+
+	files := []*drive.File{f}
+	if isDir(f) {
+		files, err = self.list(f.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	PrintFileList(PrintFileListArgs{
+		Out:       self.args.Out,
+		Files:     files,
+		NameWidth: 40,
+	})
+	return nil
+}
+
+func (self *shell) pwd(args []string) error {
+	fmt.Fprintln(self.args.Out, self.workingDir())
+	return nil
+}
+
+func (self *shell) get(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Usage: %s", shellCommands["get"].usage)
+	}
+
+	f, err := self.stat(args[0])
+	if err != nil {
+		return err
+	}
+
+	localPath := "."
+	if len(args) > 1 {
+		localPath = args[1]
+	}
+
+	return self.drive.Download(DownloadArgs{
+		Out:             self.args.Out,
+		Progress:        self.args.Progress,
+		Id:              f.Id,
+		Path:            localPath,
+		Recursive:       isDir(f),
+		FollowShortcuts: true,
+		Timeout:         self.args.Timeout,
+		Workers:         1,
+	})
+}
+
+func (self *shell) put(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Usage: %s", shellCommands["put"].usage)
+	}
+
+	info, err := os.Stat(args[0])
+	if err != nil {
+		return fmt.Errorf("Failed to read %s: %s", args[0], err)
+	}
+
+	target := "."
+	if len(args) > 1 {
+		target = args[1]
+	}
+
+	dir, err := self.statDir(target)
+	if err != nil {
+		return err
+	}
+
+	return self.drive.Upload(UploadArgs{
+		Out:       self.args.Out,
+		Progress:  self.args.Progress,
+		Path:      args[0],
+		Parents:   []string{dir.Id},
+		Recursive: info.IsDir(),
+		ChunkSize: self.args.ChunkSize,
+		Timeout:   self.args.Timeout,
+		Sessions:  self.args.Sessions,
+	})
+}
+
+func (self *shell) mv(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("Usage: %s", shellCommands["mv"].usage)
+	}
+
+	f, err := self.stat(args[0])
+	if err != nil {
+		return err
+	}
+
+	if dest, err := self.stat(args[1]); err == nil && isDir(dest) {
+		return self.drive.Move(MoveArgs{
+			Out:      self.args.Out,
+			Id:       f.Id,
+			FolderId: dest.Id,
+		})
+	}
+
+	// A target in the same directory that does not exist is a new name
+	srcRoot, srcPath := splitShellPath(self.absPath(args[0]))
+	destRoot, destPath := splitShellPath(self.absPath(args[1]))
+	if srcRoot != destRoot || path.Dir(srcPath) != path.Dir(destPath) {
+		return fmt.Errorf("'%s' is not a directory", args[1])
+	}
+
+	return self.drive.Rename(RenameArgs{
+		Out:  self.args.Out,
+		Id:   f.Id,
+		Name: path.Base(destPath),
+	})
+}
+
+func (self *shell) rm(args []string) error {
+	var recursive, permanent bool
+	var paths []string
+
+	for _, arg := range args {
+		switch arg {
+		case "-r", "--recursive":
+			recursive = true
+		case "--permanent":
+			permanent = true
+		default:
+			paths = append(paths, arg)
+		}
+	}
+
+	if len(paths) != 1 {
+		return fmt.Errorf("Usage: %s", shellCommands["rm"].usage)
+	}
+
+	f, err := self.stat(paths[0])
+	if err != nil {
+		return err
+	}
+
+	return self.drive.Delete(DeleteArgs{
+		Out:       self.args.Out,
+		Id:        f.Id,
+		Recursive: recursive,
+		Permanent: permanent,
+	})
+}
+
+func (self *shell) mkdir(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: %s", shellCommands["mkdir"].usage)
+	}
+
+	root, dirPath := splitShellPath(self.absPath(args[0]))
+	parent, err := self.statDir(root + path.Dir(dirPath))
+	if err != nil {
+		return err
+	}
+
+	return self.drive.Mkdir(MkdirArgs{
+		Out:     self.args.Out,
+		Name:    path.Base(dirPath),
+		Parents: []string{parent.Id},
+	})
+}
+
+func (self *shell) info(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: %s", shellCommands["info"].usage)
+	}
+
+	f, err := self.stat(args[0])
+	if err != nil {
+		return err
+	}
+
+	return self.drive.Info(FileInfoArgs{
+		Out: self.args.Out,
+		Id:  f.Id,
+	})
+}
+
+func (self *shell) share(args []string) error {
+	if len(args) == 0 || len(args) > 3 {
+		return fmt.Errorf("Usage: %s", shellCommands["share"].usage)
+	}
+
+	f, err := self.stat(args[0])
+	if err != nil {
+		return err
+	}
+
+	shareArgs := ShareArgs{
+		Out:    self.args.Out,
+		FileId: f.Id,
+		Role:   "reader",
+		Type:   "anyone",
+	}
+
+	if len(args) > 1 {
+		shareArgs.Type = "user"
+		shareArgs.Email = args[1]
+	}
+
+	if len(args) > 2 {
+		shareArgs.Role = args[2]
+	}
+
+	return self.drive.Share(shareArgs)
+}
+
+func (self *shell) help(args []string) error {
+	var names []string
+	for name := range shellCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cmd := shellCommands[name]
+		fmt.Fprintf(self.args.Out, "  %-30s %s\n", cmd.usage, cmd.description)
+	}
+
+	return nil
+}
+
+// Completes the last word of the line as a command or a path. Returns the
+// completed line, or the candidates when there are several and nothing to add
+func (self *shell) complete(line string) (string, []string) {
+	start := lastShellWordStart(line)
+	word := unescapeShellWord(line[start:])
+
+	var candidates []string
+
+	if strings.TrimSpace(line[:start]) == "" {
+		for name := range shellCommands {
+			if strings.HasPrefix(name, word) {
+				candidates = append(candidates, name+" ")
+			}
+		}
+		sort.Strings(candidates)
+		return completeWord(line, start, "", word, candidates)
+	}
+
+	dirPart, prefix := "", word
+	if i := strings.LastIndex(word, "/"); i >= 0 {
+		dirPart, prefix = word[:i+1], word[i+1:]
+	}
+
+	dir := dirPart
+	if dir == "" {
+		dir = "."
+	}
+
+	dirId, err := self.drive.resolveId(self.absPath(dir))
+	if err != nil {
+		return line, nil
+	}
+
+	files, err := self.list(dirId)
+	if err != nil {
+		return line, nil
+	}
+
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, prefix) {
+			continue
+		}
+
+		if isDir(f) {
+			candidates = append(candidates, f.Name+"/")
+		} else {
+			candidates = append(candidates, f.Name+" ")
+		}
+	}
+
+	return completeWord(line, start, dirPart, prefix, candidates)
+}
+
+func completeWord(line string, start int, dirPart, prefix string, candidates []string) (string, []string) {
+	if len(candidates) == 0 {
+		return line, nil
+	}
+
+	if len(candidates) == 1 {
+		name := candidates[0]
+		suffix := ""
+		if strings.HasSuffix(name, " ") {
+			name, suffix = strings.TrimSuffix(name, " "), " "
+		}
+		return line[:start] + escapeShellWord(dirPart+name) + suffix, nil
+	}
+
+	common := commonPrefix(candidates)
+	if len(common) > len(prefix) {
+		return line[:start] + escapeShellWord(dirPart+common), nil
+	}
+
+	var names []string
+	for _, c := range candidates {
+		names = append(names, strings.TrimSuffix(c, " "))
+	}
+
+	return line, names
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+
+	for _, v := range values[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
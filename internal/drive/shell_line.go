@@ -0,0 +1,266 @@
+package drive
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+type completeFunc func(line string) (string, []string)
+
+// Reads lines with history and tab completion when reading from a terminal.
+// The terminal is switched to raw mode with stty while a line is read, so
+// commands run with the terminal as it was. Other input is read line by line
+type lineReader struct {
+	in       *bufio.Reader
+	terminal *os.File
+	out      io.Writer
+	complete completeFunc
+	history  []string
+}
+
+func newLineReader(in io.Reader, out io.Writer, complete completeFunc) *lineReader {
+	reader := &lineReader{
+		in:       bufio.NewReader(in),
+		out:      out,
+		complete: complete,
+	}
+
+	if f, ok := in.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			reader.terminal = f
+		}
+	}
+
+	return reader
+}
+
+func (self *lineReader) readLine(prompt string) (string, error) {
+	if self.terminal == nil {
+		return self.readPlainLine(prompt)
+	}
+
+	state, err := self.stty("-g")
+	if err != nil {
+		return self.readPlainLine(prompt)
+	}
+
+	if _, err := self.stty("-icanon", "-echo", "-isig", "min", "1"); err != nil {
+		return self.readPlainLine(prompt)
+	}
+	defer self.stty(strings.TrimSpace(state))
+
+	return self.readEditedLine(prompt)
+}
+
+func (self *lineReader) readPlainLine(prompt string) (string, error) {
+	fmt.Fprint(self.out, prompt)
+
+	line, err := self.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (self *lineReader) readEditedLine(prompt string) (string, error) {
+	line := ""
+	historyIndex := len(self.history)
+
+	redraw := func() {
+		fmt.Fprintf(self.out, "\r\033[K%s%s", prompt, line)
+	}
+	redraw()
+
+	for {
+		b, err := self.in.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case b == '\r' || b == '\n':
+			fmt.Fprint(self.out, "\n")
+			if strings.TrimSpace(line) != "" {
+				self.history = append(self.history, line)
+			}
+			return line, nil
+
+		case b == 127 || b == 8:
+			if line != "" {
+				_, size := utf8.DecodeLastRuneInString(line)
+				line = line[:len(line)-size]
+			}
+
+		case b == '\t':
+			completed, candidates := self.complete(line)
+			if len(candidates) > 0 {
+				fmt.Fprintf(self.out, "\n%s\n", strings.Join(candidates, "  "))
+			}
+			line = completed
+
+		// Ctrl-C discards the line
+		case b == 3:
+			fmt.Fprint(self.out, "^C\n")
+			line = ""
+
+		// Ctrl-D on an empty line ends the session
+		case b == 4:
+			if line == "" {
+				return "", io.EOF
+			}
+
+		// Ctrl-U clears the line
+		case b == 21:
+			line = ""
+
+		// Arrow keys, up and down walk through the history. Other keys
+		// that send escape sequences are ignored
+		case b == 27:
+			seq, err := self.readEscapeSequence()
+			if err != nil {
+				return "", err
+			}
+
+			if (seq == "[A" || seq == "OA") && historyIndex > 0 {
+				historyIndex--
+				line = self.history[historyIndex]
+			} else if (seq == "[B" || seq == "OB") && historyIndex < len(self.history) {
+				historyIndex++
+				line = ""
+				if historyIndex < len(self.history) {
+					line = self.history[historyIndex]
+				}
+			}
+
+		case b < 32:
+			// Ignore other control characters
+
+		default:
+			// Multi-byte characters arrive one byte at a time
+			line += string([]byte{b})
+		}
+
+		redraw()
+	}
+}
+
+// Reads the rest of an escape sequence, e.g. "[A" for the up key or "[3~"
+// for delete. A CSI sequence ends with a byte in the range @ to ~, any
+// parameters before it are part of the sequence
+func (self *lineReader) readEscapeSequence() (string, error) {
+	b, err := self.in.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	seq := []byte{b}
+
+	switch b {
+	case '[':
+		for {
+			b, err := self.in.ReadByte()
+			if err != nil {
+				return "", err
+			}
+			seq = append(seq, b)
+
+			if b >= 0x40 && b <= 0x7e {
+				break
+			}
+		}
+	case 'O':
+		// Some terminals send the arrow and home/end keys as ESC O <key>
+		b, err := self.in.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		seq = append(seq, b)
+	}
+
+	return string(seq), nil
+}
+
+func (self *lineReader) stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = self.terminal
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Splits a command line into words. Words are separated by spaces, which
+// can be kept in a word with quotes or a backslash
+func splitShellWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	inWord := false
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("Missing closing quote")
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// Returns where the last word of the line starts, spaces escaped with a backslash are part of the word
+func lastShellWordStart(line string) int {
+	for i := len(line) - 1; i >= 0; i-- {
+		if line[i] == ' ' && (i == 0 || line[i-1] != '\\') {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func unescapeShellWord(word string) string {
+	words, err := splitShellWords(word)
+	if err != nil || len(words) == 0 {
+		return word
+	}
+	return words[0]
+}
+
+func escapeShellWord(word string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ` `, `\ `, `"`, `\"`, `'`, `\'`)
+	return replacer.Replace(word)
+}
@@ -0,0 +1,65 @@
+package drive
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: "", want: nil},
+		{line: "   ", want: nil},
+		{line: "ls", want: []string{"ls"}},
+		{line: "  cd \tdir  ", want: []string{"cd", "dir"}},
+		{line: `get "My Documents/a b.txt"`, want: []string{"get", "My Documents/a b.txt"}},
+		{line: `get 'My Documents'`, want: []string{"get", "My Documents"}},
+		{line: `get My\ Documents`, want: []string{"get", "My Documents"}},
+		{line: `get a"b c"d`, want: []string{"get", "ab cd"}},
+		{line: `get "say \"hi\""`, want: []string{"get", `say "hi"`}},
+		{line: `get 'C:\dir'`, want: []string{"get", `C:\dir`}},
+		{line: `get "John's"`, want: []string{"get", "John's"}},
+		{line: `mkdir ""`, want: []string{"mkdir", ""}},
+		{line: "cd été/日本", want: []string{"cd", "été/日本"}},
+	}
+
+	for _, test := range tests {
+		got, err := splitShellWords(test.line)
+		if err != nil {
+			t.Errorf("splitShellWords(%q) failed: %s", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitShellWords(%q) = %q, want %q", test.line, got, test.want)
+		}
+	}
+}
+
+func TestSplitShellWordsErrors(t *testing.T) {
+	for _, line := range []string{`get "a`, `get 'a b`, `get "a'`} {
+		if _, err := splitShellWords(line); err == nil {
+			t.Errorf("splitShellWords(%q) succeeded, want an error", line)
+		}
+	}
+}
+
+func TestLastShellWordStart(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{line: "", want: 0},
+		{line: "ls", want: 0},
+		{line: "cd dir", want: 3},
+		{line: "cd ", want: 3},
+		{line: `cd My\ Doc`, want: 3},
+	}
+
+	for _, test := range tests {
+		if got := lastShellWordStart(test.line); got != test.want {
+			t.Errorf("lastShellWordStart(%q) = %d, want %d", test.line, got, test.want)
+		}
+	}
+}
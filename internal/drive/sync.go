@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 )
@@ -41,7 +42,7 @@ const (
 	KeepLargest
 )
 
-func (self *Drive) prepareSyncFiles(localPath string, root *drive.File, cmp FileComparer) (*syncFiles, error) {
+func (self *Drive) prepareSyncFiles(localPath string, root *drive.File, cmp FileComparer, stateDir string, fullScan bool, out io.Writer) (*syncFiles, error) {
 	localCh := make(chan struct {
 		files []*LocalFile
 		err   error
@@ -52,7 +53,7 @@ func (self *Drive) prepareSyncFiles(localPath string, root *drive.File, cmp File
 	})
 
 	go func() {
-		files, err := prepareLocalFiles(localPath)
+		files, err := prepareLocalFiles(localPath, out)
 		localCh <- struct {
 			files []*LocalFile
 			err   error
@@ -60,7 +61,16 @@ func (self *Drive) prepareSyncFiles(localPath string, root *drive.File, cmp File
 	}()
 
 	go func() {
-		files, err := self.prepareRemoteFiles(root, "")
+		var files []*RemoteFile
+		var err error
+
+		// Remote files are only listed incrementally when there is a place to keep the snapshot
+		if stateDir == "" {
+			files, err = self.prepareRemoteFiles(root, "")
+		} else {
+			files, err = self.prepareRemoteFilesFromSnapshot(root, stateDir, fullScan)
+		}
+
 		remoteCh <- struct {
 			files []*RemoteFile
 			err   error
@@ -82,11 +92,12 @@ func (self *Drive) prepareSyncFiles(localPath string, root *drive.File, cmp File
 		local:   local.files,
 		remote:  remote.files,
 		compare: cmp,
+		mutex:   &sync.RWMutex{},
 	}, nil
 }
 
 func (self *Drive) isSyncFile(id string) (bool, error) {
-	f, err := self.service.Files.Get(id).Fields("appProperties").Do()
+	f, err := self.service.Files.Get(id).SupportsAllDrives(true).Fields("appProperties").Do()
 	if err != nil {
 		return false, fmt.Errorf("Failed to get file: %s", err)
 	}
@@ -95,9 +106,7 @@ func (self *Drive) isSyncFile(id string) (bool, error) {
 	return ok, nil
 }
 
-func prepareLocalFiles(root string) ([]*LocalFile, error) {
-	var files []*LocalFile
-
+func prepareLocalFiles(root string, out io.Writer) ([]*LocalFile, error) {
 	// Get absolute root path
 	absRootPath, err := filepath.Abs(root)
 	if err != nil {
@@ -110,7 +119,15 @@ func prepareLocalFiles(root string) ([]*LocalFile, error) {
 		return nil, err
 	}
 
-	err = filepath.Walk(absRootPath, func(absPath string, info os.FileInfo, err error) error {
+	return walkLocalFiles(absRootPath, absRootPath, shouldIgnore, out)
+}
+
+// Returns the file at startPath and all files below it, with paths relative to the sync root.
+// Skipped partial downloads are reported to out
+func walkLocalFiles(absRootPath string, startPath string, shouldIgnore ignoreFunc, out io.Writer) ([]*LocalFile, error) {
+	var files []*LocalFile
+
+	err := filepath.Walk(startPath, func(absPath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -136,6 +153,14 @@ func prepareLocalFiles(root string) ([]*LocalFile, error) {
 			return nil
 		}
 
+		// Skip partial downloads, they are completed or discarded by the next download
+		if !info.IsDir() && isIncompleteDownload(absPath) {
+			if !strings.HasSuffix(relPath, IncompleteStateSuffix) {
+				fmt.Fprintf(out, "Skipping incomplete download '%s'\n", relPath)
+			}
+			return nil
+		}
+
 		files = append(files, &LocalFile{
 			absPath: absPath,
 			relPath: relPath,
@@ -153,10 +178,19 @@ func prepareLocalFiles(root string) ([]*LocalFile, error) {
 }
 
 func (self *Drive) prepareRemoteFiles(rootDir *drive.File, sortOrder string) ([]*RemoteFile, error) {
-	// Find all files which has rootDir as root
+	files, err := self.listSyncFiles(rootDir, sortOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	return self.newRemoteFiles(rootDir, files)
+}
+
+// Find all files which has rootDir as root
+func (self *Drive) listSyncFiles(rootDir *drive.File, sortOrder string) ([]*drive.File, error) {
 	listArgs := listAllFilesArgs{
-		query:     fmt.Sprintf("appProperties has {key='syncRootId' and value='%s'}", rootDir.Id),
-		fields:    []googleapi.Field{"nextPageToken", "files(id,name,parents,md5Checksum,mimeType,size,modifiedTime)"},
+		query:     fmt.Sprintf("appProperties has {key='syncRootId' and value='%s'} and trashed = false", rootDir.Id),
+		fields:    []googleapi.Field{"nextPageToken", "files(id,name,parents,md5Checksum,sha1Checksum,sha256Checksum,mimeType,size,modifiedTime,appProperties)"},
 		sortOrder: sortOrder,
 	}
 	files, err := self.listAllFiles(listArgs)
@@ -164,6 +198,16 @@ func (self *Drive) prepareRemoteFiles(rootDir *drive.File, sortOrder string) ([]
 		return nil, fmt.Errorf("Failed listing files: %s", err)
 	}
 
+	return files, nil
+}
+
+func (self *Drive) newRemoteFiles(rootDir *drive.File, files []*drive.File) ([]*RemoteFile, error) {
+	// Paths are made of the plain names
+	files, err := self.withPlainNames(files)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := checkFiles(files); err != nil {
 		return nil, err
 	}
@@ -299,6 +343,7 @@ type syncFiles struct {
 	local   []*LocalFile
 	remote  []*RemoteFile
 	compare FileComparer
+	mutex   *sync.RWMutex
 }
 
 type FileComparer interface {
@@ -317,11 +362,18 @@ func (self LocalFile) Modified() time.Time {
 	return self.info.ModTime()
 }
 
+// The md5 and size of encrypted files are those of the plain content
 func (self RemoteFile) Md5() string {
+	if isEncrypted(self.file) {
+		return self.file.AppProperties[plainMd5Property]
+	}
 	return self.file.Md5Checksum
 }
 
 func (self RemoteFile) Size() int64 {
+	if isEncrypted(self.file) {
+		return plainSize(self.file)
+	}
 	return self.file.Size
 }
 
@@ -501,6 +553,9 @@ func (self *syncFiles) findRemoteByPath(relPath string) (*RemoteFile, bool) {
 		return self.root, true
 	}
 
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+
 	for _, rf := range self.remote {
 		if relPath == rf.relPath {
 			return rf, true
@@ -510,6 +565,26 @@ func (self *syncFiles) findRemoteByPath(relPath string) (*RemoteFile, bool) {
 	return nil, false
 }
 
+// Adds a file that has been uploaded, may be called by concurrent workers
+func (self *syncFiles) addRemote(rf *RemoteFile) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.remote = append(self.remote, rf)
+}
+
+func (self *syncFiles) removeRemote(rf *RemoteFile) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for i, f := range self.remote {
+		if f == rf {
+			self.remote = append(self.remote[:i], self.remote[i+1:]...)
+			return
+		}
+	}
+}
+
 func (self *syncFiles) findLocalByPath(relPath string) (*LocalFile, bool) {
 	for _, lf := range self.local {
 		if relPath == lf.relPath {
@@ -0,0 +1,865 @@
+package drive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/imzza/gdrive/internal/utils"
+)
+
+type BidirectionalSyncArgs struct {
+	Out        io.Writer
+	Progress   io.Writer
+	Path       string
+	RootId     string
+	DryRun     bool
+	ChunkSize  int64
+	Timeout    time.Duration
+	Resolution ConflictResolution
+	Comparer   FileComparer
+	Workers    int
+	StateDir   string
+	FullScan   bool
+	Permanent  bool
+	Verify     bool
+	Sessions   *UploadSessionStore
+}
+
+func (self *Drive) BidirectionalSync(args BidirectionalSyncArgs) error {
+	if err := self.resolvePaths(&args.RootId); err != nil {
+		return err
+	}
+
+	if args.ChunkSize > intMax()-1 {
+		return fmt.Errorf("Chunk size is to big, max chunk size for this computer is %d", intMax()-1)
+	}
+
+	if args.StateDir == "" {
+		return fmt.Errorf("Bidirectional sync requires a state directory")
+	}
+
+	// Ensure that output lines from concurrent workers are not interleaved
+	args.Out = newSyncWriter(args.Out)
+
+	// Progress bars from several files at once are unreadable, hide them
+	if args.Workers > 1 {
+		args.Progress = io.Discard
+	}
+
+	fmt.Fprintln(args.Out, "Starting sync...")
+	started := time.Now()
+
+	// Create root directory if it does not exist
+	rootDir, err := self.prepareSyncRoot(args.RootId)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(args.Out, "Collecting local and remote file information...")
+	files, err := self.prepareSyncFiles(args.Path, rootDir, args.Comparer, args.StateDir, args.FullScan, args.Out)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Found %d local files and %d remote files\n", len(files.local), len(files.remote))
+
+	base, err := loadSyncBase(syncBasePath(args.StateDir, rootDir.Id))
+	if err != nil {
+		return fmt.Errorf("Failed to load sync state: %s", err)
+	}
+
+	items := prepareSyncItems(files, base)
+	decideSyncActions(items, files.compare, args.Resolution)
+
+	conflicts := filterSyncItems(items, syncConflict, false)
+	conflicts = append(conflicts, filterSyncItems(items, syncConflict, true)...)
+	if len(conflicts) > 0 {
+		fmt.Fprintf(args.Out, "\n%d files have changed on both sides since the last sync\n", len(conflicts))
+		formatSyncConflicts(conflicts, args.Out)
+	}
+
+	// Ensure that there is enough free space on drive
+	var uploadFiles []*LocalFile
+	for _, item := range filterSyncItems(items, syncUpload, false) {
+		uploadFiles = append(uploadFiles, item.local)
+	}
+	if ok, msg := self.checkRemoteFreeSpace(uploadFiles, nil); !ok {
+		return fmt.Errorf("%s", msg)
+	}
+
+	// Record the files that already are equal on both sides
+	for _, item := range items {
+		if item.action == syncNone {
+			base.record(item)
+		}
+	}
+
+	err = self.runBidirectionalSync(items, files, base, args)
+
+	if !args.DryRun {
+		// The state is saved even if the sync failed so that completed transfers are not repeated
+		if saveErr := base.save(); saveErr != nil && err == nil {
+			err = fmt.Errorf("Failed to save sync state: %s", saveErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Sync finished in %s\n", time.Since(started))
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d conflicts were not resolved, use --keep-local, --keep-remote or --keep-largest to resolve them", len(conflicts))
+	}
+
+	return nil
+}
+
+func (self *Drive) runBidirectionalSync(items []*syncItem, files *syncFiles, base *syncBase, args BidirectionalSyncArgs) error {
+	uploadArgs := UploadSyncArgs{
+		Out:       args.Out,
+		Progress:  args.Progress,
+		Path:      args.Path,
+		RootId:    files.root.file.Id,
+		DryRun:    args.DryRun,
+		ChunkSize: args.ChunkSize,
+		Timeout:   args.Timeout,
+		Workers:   args.Workers,
+		Permanent: args.Permanent,
+		Verify:    args.Verify,
+		Sessions:  args.Sessions,
+	}
+
+	downloadArgs := DownloadSyncArgs{
+		Out:      args.Out,
+		Progress: args.Progress,
+		RootId:   files.root.file.Id,
+		Path:     args.Path,
+		DryRun:   args.DryRun,
+		Timeout:  args.Timeout,
+		Workers:  args.Workers,
+		Verify:   args.Verify,
+	}
+
+	err := self.createBidirectionalRemoteDirs(items, files, base, uploadArgs)
+	if err != nil {
+		return err
+	}
+
+	err = self.createBidirectionalLocalDirs(items, base, downloadArgs)
+	if err != nil {
+		return err
+	}
+
+	err = self.uploadBidirectionalFiles(items, files, base, uploadArgs)
+	if err != nil {
+		return err
+	}
+
+	err = self.downloadBidirectionalFiles(items, base, downloadArgs)
+	if err != nil {
+		return err
+	}
+
+	err = self.deleteBidirectionalRemoteFiles(items, files, base, uploadArgs)
+	if err != nil {
+		return err
+	}
+
+	return self.deleteBidirectionalLocalFiles(items, base, downloadArgs)
+}
+
+func (self *Drive) createBidirectionalRemoteDirs(items []*syncItem, files *syncFiles, base *syncBase, args UploadSyncArgs) error {
+	// Items are sorted by path, so parent directories are created before their children
+	missingDirs := filterSyncItems(items, syncUpload, true)
+	missingCount := len(missingDirs)
+
+	if missingCount > 0 {
+		fmt.Fprintf(args.Out, "\n%d remote directories are missing\n", missingCount)
+	}
+
+	for i, item := range missingDirs {
+		parentPath := parentFilePath(item.relPath)
+		parent, ok := files.findRemoteByPath(parentPath)
+		if !ok {
+			return fmt.Errorf("Could not find remote directory with path '%s'", parentPath)
+		}
+
+		fmt.Fprintf(args.Out, "[%04d/%04d] Creating directory %s\n", i+1, missingCount, filepath.Join(files.root.file.Name, item.relPath))
+
+		f, err := self.createMissingRemoteDir(createMissingRemoteDirArgs{
+			name:     item.local.info.Name(),
+			parentId: parent.file.Id,
+			rootId:   args.RootId,
+			dryRun:   args.DryRun,
+		})
+		if err != nil {
+			return err
+		}
+
+		item.remote = &RemoteFile{relPath: item.relPath, file: f}
+		files.remote = append(files.remote, item.remote)
+		base.record(item)
+	}
+
+	return nil
+}
+
+func (self *Drive) createBidirectionalLocalDirs(items []*syncItem, base *syncBase, args DownloadSyncArgs) error {
+	missingDirs := filterSyncItems(items, syncDownload, true)
+	missingCount := len(missingDirs)
+
+	if missingCount > 0 {
+		fmt.Fprintf(args.Out, "\n%d local directories are missing\n", missingCount)
+	}
+
+	for i, item := range missingDirs {
+		absPath, err := filepath.Abs(filepath.Join(args.Path, item.relPath))
+		if err != nil {
+			return fmt.Errorf("Failed to determine local absolute path: %s", err)
+		}
+		fmt.Fprintf(args.Out, "[%04d/%04d] Creating directory %s\n", i+1, missingCount, filepath.Join(filepath.Base(args.Path), item.relPath))
+
+		if args.DryRun {
+			continue
+		}
+
+		err = os.MkdirAll(absPath, 0775)
+		if err != nil {
+			return fmt.Errorf("Failed to create directory: %s", err)
+		}
+
+		if err := item.statLocal(absPath); err != nil {
+			return err
+		}
+		base.record(item)
+	}
+
+	return nil
+}
+
+func (self *Drive) uploadBidirectionalFiles(items []*syncItem, files *syncFiles, base *syncBase, args UploadSyncArgs) error {
+	uploadItems := filterSyncItems(items, syncUpload, false)
+	uploadCount := len(uploadItems)
+
+	if uploadCount > 0 {
+		fmt.Fprintf(args.Out, "\n%d local files are new or have changed\n", uploadCount)
+	}
+
+	return runWorkers(args.Workers, uploadCount, func(i int) error {
+		item := uploadItems[i]
+		remotePath := filepath.Join(files.root.file.Name, item.relPath)
+
+		var err error
+		var remote *RemoteFile
+
+		if item.remote == nil {
+			parentPath := parentFilePath(item.relPath)
+			parent, ok := files.findRemoteByPath(parentPath)
+			if !ok {
+				return fmt.Errorf("Could not find remote directory with path '%s'", parentPath)
+			}
+
+			fmt.Fprintf(args.Out, "[%04d/%04d] Uploading %s -> %s\n", i+1, uploadCount, item.relPath, remotePath)
+
+			remote = &RemoteFile{relPath: item.relPath}
+			remote.file, err = self.uploadMissingFile(parent.file.Id, item.local, args, 0)
+		} else {
+			fmt.Fprintf(args.Out, "[%04d/%04d] Updating %s -> %s\n", i+1, uploadCount, item.relPath, remotePath)
+
+			remote = &RemoteFile{relPath: item.relPath}
+			remote.file, err = self.updateChangedFile(&changedFile{item.local, item.remote}, args, 0)
+		}
+		if err != nil || args.DryRun {
+			return err
+		}
+
+		item.remote = remote
+		base.record(item)
+		return nil
+	})
+}
+
+func (self *Drive) downloadBidirectionalFiles(items []*syncItem, base *syncBase, args DownloadSyncArgs) error {
+	downloadItems := filterSyncItems(items, syncDownload, false)
+	downloadCount := len(downloadItems)
+
+	if downloadCount > 0 {
+		fmt.Fprintf(args.Out, "\n%d remote files are new or have changed\n", downloadCount)
+	}
+
+	return runWorkers(args.Workers, downloadCount, func(i int) error {
+		item := downloadItems[i]
+		absPath, err := filepath.Abs(filepath.Join(args.Path, item.relPath))
+		if err != nil {
+			return fmt.Errorf("Failed to determine local absolute path: %s", err)
+		}
+		fmt.Fprintf(args.Out, "[%04d/%04d] Downloading %s -> %s\n", i+1, downloadCount, item.relPath, filepath.Join(filepath.Base(args.Path), item.relPath))
+
+		err = self.downloadRemoteFile(item.remote, absPath, args)
+		if err != nil || args.DryRun {
+			return err
+		}
+
+		if err := item.statLocal(absPath); err != nil {
+			return err
+		}
+		base.record(item)
+		return nil
+	})
+}
+
+func (self *Drive) deleteBidirectionalRemoteFiles(items []*syncItem, files *syncFiles, base *syncBase, args UploadSyncArgs) error {
+	deleteItems := filterSyncItems(items, syncDeleteRemote, false)
+	deleteItems = append(deleteItems, filterSyncItems(items, syncDeleteRemote, true)...)
+	deleteCount := len(deleteItems)
+
+	if deleteCount > 0 {
+		fmt.Fprintf(args.Out, "\n%d files were deleted locally\n", deleteCount)
+	}
+
+	// Sort files so that the files with the longest path comes first
+	sort.SliceStable(deleteItems, func(i, j int) bool {
+		return len(deleteItems[i].relPath) > len(deleteItems[j].relPath)
+	})
+
+	for i, item := range deleteItems {
+		fmt.Fprintf(args.Out, "[%04d/%04d] %s %s\n", i+1, deleteCount, remoteDeleteAction(args.Permanent), filepath.Join(files.root.file.Name, item.relPath))
+
+		err := self.deleteRemoteFile(item.remote, args)
+		if err != nil {
+			return err
+		}
+
+		if !args.DryRun {
+			base.remove(item.relPath)
+		}
+	}
+
+	return nil
+}
+
+func (self *Drive) deleteBidirectionalLocalFiles(items []*syncItem, base *syncBase, args DownloadSyncArgs) error {
+	deleteItems := filterSyncItems(items, syncDeleteLocal, false)
+	deleteItems = append(deleteItems, filterSyncItems(items, syncDeleteLocal, true)...)
+	deleteCount := len(deleteItems)
+
+	if deleteCount > 0 {
+		fmt.Fprintf(args.Out, "\n%d files were deleted remotely\n", deleteCount)
+	}
+
+	// Sort files so that the files with the longest path comes first
+	sort.SliceStable(deleteItems, func(i, j int) bool {
+		return len(deleteItems[i].relPath) > len(deleteItems[j].relPath)
+	})
+
+	for i, item := range deleteItems {
+		fmt.Fprintf(args.Out, "[%04d/%04d] Deleting %s\n", i+1, deleteCount, item.local.absPath)
+
+		if args.DryRun {
+			continue
+		}
+
+		err := os.Remove(item.local.absPath)
+		if err != nil {
+			return fmt.Errorf("Failed to delete local file: %s", err)
+		}
+
+		base.remove(item.relPath)
+	}
+
+	return nil
+}
+
+type syncAction int
+
+const (
+	syncNone syncAction = iota
+	syncUpload
+	syncDownload
+	syncDeleteRemote
+	syncDeleteLocal
+	syncConflict
+)
+
+type syncChange int
+
+const (
+	syncUnchanged syncChange = iota
+	syncAdded
+	syncModified
+	syncDeleted
+)
+
+func (self syncChange) String() string {
+	switch self {
+	case syncAdded:
+		return "added"
+	case syncModified:
+		return "modified"
+	case syncDeleted:
+		return "deleted"
+	}
+	return "unchanged"
+}
+
+// A path of the sync root together with its local, remote and last synced state
+type syncItem struct {
+	relPath      string
+	local        *LocalFile
+	remote       *RemoteFile
+	base         *syncBaseFile
+	localChange  syncChange
+	remoteChange syncChange
+	action       syncAction
+}
+
+func prepareSyncItems(files *syncFiles, base *syncBase) []*syncItem {
+	itemMap := map[string]*syncItem{}
+
+	getItem := func(relPath string) *syncItem {
+		item, found := itemMap[relPath]
+		if !found {
+			item = &syncItem{relPath: relPath}
+			itemMap[relPath] = item
+		}
+		return item
+	}
+
+	for _, lf := range files.local {
+		getItem(lf.relPath).local = lf
+	}
+
+	for _, rf := range files.remote {
+		getItem(rf.relPath).remote = rf
+	}
+
+	for relPath, bf := range base.files {
+		getItem(relPath).base = bf
+	}
+
+	var items []*syncItem
+	for _, item := range itemMap {
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].relPath < items[j].relPath
+	})
+
+	return items
+}
+
+// Decides what to do with every item by comparing both sides with the last synced
+// state. Changes on one side are propagated to the other, while changes on both
+// sides are conflicts unless they are equal or a conflict resolution is given
+func decideSyncActions(items []*syncItem, cmp FileComparer, resolution ConflictResolution) {
+	for _, item := range items {
+		// Google documents can't be downloaded and are left alone
+		if item.isDir() || item.isDocument() {
+			continue
+		}
+
+		item.localChange = item.detectLocalChange()
+		item.remoteChange = item.detectRemoteChange()
+		item.action = item.decideFileAction(cmp, resolution)
+	}
+
+	// Directories are decided after the files they contain, children before parents
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if !item.isDir() {
+			continue
+		}
+
+		item.localChange = item.detectLocalChange()
+		item.remoteChange = item.detectRemoteChange()
+		item.action = item.decideDirAction(items)
+	}
+}
+
+func (self *syncItem) isDir() bool {
+	if self.local != nil {
+		return self.local.info.IsDir()
+	}
+	if self.remote != nil {
+		return isDir(self.remote.file)
+	}
+	return self.base.Dir
+}
+
+func (self *syncItem) isDocument() bool {
+	return self.remote != nil && !isDir(self.remote.file) && !isBinary(self.remote.file)
+}
+
+// A directory on one side and a file on the other
+func (self *syncItem) typeMismatch() bool {
+	return self.local != nil && self.remote != nil && self.local.info.IsDir() != isDir(self.remote.file)
+}
+
+func (self *syncItem) detectLocalChange() syncChange {
+	if self.base == nil {
+		if self.local != nil {
+			return syncAdded
+		}
+		return syncUnchanged
+	}
+
+	if self.local == nil {
+		return syncDeleted
+	}
+
+	if self.local.info.IsDir() {
+		return syncUnchanged
+	}
+
+	if self.local.Size() != self.base.Size {
+		return syncModified
+	}
+
+	if self.local.Modified().UnixNano() == self.base.Modified {
+		return syncUnchanged
+	}
+
+	// Modification time has changed, only the content can tell if the file was modified
+	if utils.Md5sum(self.local.absPath) != self.base.Md5 {
+		return syncModified
+	}
+
+	return syncUnchanged
+}
+
+func (self *syncItem) detectRemoteChange() syncChange {
+	if self.base == nil {
+		if self.remote != nil {
+			return syncAdded
+		}
+		return syncUnchanged
+	}
+
+	if self.remote == nil {
+		return syncDeleted
+	}
+
+	if isDir(self.remote.file) {
+		return syncUnchanged
+	}
+
+	if self.remote.file.Id != self.base.RemoteId || self.remote.Md5() != self.base.Md5 {
+		return syncModified
+	}
+
+	return syncUnchanged
+}
+
+// What is known about both sides of a file when deciding what to do with it
+type fileSyncState struct {
+	localChange  syncChange
+	remoteChange syncChange
+	// A directory on one side and a file on the other
+	typeMismatch bool
+	// Both sides exist with the same content, only compared when both have changed
+	sameContent bool
+	// Sizes are -1 for a side that does not exist
+	localSize  int64
+	remoteSize int64
+}
+
+func (self *syncItem) fileSyncState(cmp FileComparer) fileSyncState {
+	state := fileSyncState{
+		localChange:  self.localChange,
+		remoteChange: self.remoteChange,
+		typeMismatch: self.typeMismatch(),
+		localSize:    -1,
+		remoteSize:   -1,
+	}
+
+	if self.local != nil {
+		state.localSize = self.local.Size()
+	}
+
+	if self.remote != nil {
+		state.remoteSize = self.remote.Size()
+	}
+
+	// Comparing may require hashing the local file
+	bothChanged := self.localChange != syncUnchanged && self.remoteChange != syncUnchanged
+	if bothChanged && !state.typeMismatch && self.local != nil && self.remote != nil {
+		state.sameContent = !cmp.Changed(self.local, self.remote)
+	}
+
+	return state
+}
+
+func (self *syncItem) decideFileAction(cmp FileComparer, resolution ConflictResolution) syncAction {
+	return decideFileAction(self.fileSyncState(cmp), resolution)
+}
+
+func decideFileAction(state fileSyncState, resolution ConflictResolution) syncAction {
+	if state.typeMismatch {
+		return syncConflict
+	}
+
+	localChanged := state.localChange != syncUnchanged
+	remoteChanged := state.remoteChange != syncUnchanged
+
+	switch {
+	case !localChanged && !remoteChanged:
+		return syncNone
+	case localChanged && !remoteChanged:
+		if state.localChange == syncDeleted {
+			return syncDeleteRemote
+		}
+		return syncUpload
+	case !localChanged && remoteChanged:
+		if state.remoteChange == syncDeleted {
+			return syncDeleteLocal
+		}
+		return syncDownload
+	}
+
+	// Both sides have changed, which is fine if they changed the same way
+	if state.localChange == syncDeleted && state.remoteChange == syncDeleted {
+		return syncNone
+	}
+
+	if state.sameContent {
+		return syncNone
+	}
+
+	return resolveConflict(state, resolution)
+}
+
+func resolveConflict(state fileSyncState, resolution ConflictResolution) syncAction {
+	keepLocal := func() syncAction {
+		if state.localChange == syncDeleted {
+			return syncDeleteRemote
+		}
+		return syncUpload
+	}
+
+	keepRemote := func() syncAction {
+		if state.remoteChange == syncDeleted {
+			return syncDeleteLocal
+		}
+		return syncDownload
+	}
+
+	switch resolution {
+	case KeepLocal:
+		return keepLocal()
+	case KeepRemote:
+		return keepRemote()
+	case KeepLargest:
+		if state.localSize > state.remoteSize {
+			return keepLocal()
+		}
+		return keepRemote()
+	}
+
+	return syncConflict
+}
+
+func (self *syncItem) decideDirAction(items []*syncItem) syncAction {
+	if self.typeMismatch() {
+		return syncConflict
+	}
+
+	if self.local != nil && self.remote == nil {
+		// New local directory
+		if self.base == nil {
+			return syncUpload
+		}
+
+		// Deleted remotely, delete it locally as well unless it contains something we want to keep
+		if self.allDescendants(items, func(item *syncItem) bool { return item.local == nil || item.action == syncDeleteLocal }) {
+			return syncDeleteLocal
+		}
+
+		if !self.allDescendants(items, func(item *syncItem) bool { return item.action != syncUpload }) {
+			return syncUpload
+		}
+	}
+
+	if self.remote != nil && self.local == nil {
+		// New remote directory
+		if self.base == nil {
+			return syncDownload
+		}
+
+		// Deleted locally, delete it remotely as well unless it contains something we want to keep
+		if self.allDescendants(items, func(item *syncItem) bool { return item.remote == nil || item.action == syncDeleteRemote }) {
+			return syncDeleteRemote
+		}
+
+		if !self.allDescendants(items, func(item *syncItem) bool { return item.action != syncDownload }) {
+			return syncDownload
+		}
+	}
+
+	return syncNone
+}
+
+// Returns true if fn returns true for all items below this directory. Items are sorted
+// by path, so all descendants are found in one block after the directory itself
+func (self *syncItem) allDescendants(items []*syncItem, fn func(*syncItem) bool) bool {
+	prefix := self.relPath + string(filepath.Separator)
+
+	i := sort.Search(len(items), func(i int) bool {
+		return items[i].relPath >= prefix
+	})
+
+	for ; i < len(items) && strings.HasPrefix(items[i].relPath, prefix); i++ {
+		if !fn(items[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Updates the local part of the item after it has been written to disk
+func (self *syncItem) statLocal(absPath string) error {
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return fmt.Errorf("Failed to stat file: %s", err)
+	}
+
+	self.local = &LocalFile{
+		absPath: absPath,
+		relPath: self.relPath,
+		info:    info,
+	}
+
+	return nil
+}
+
+func filterSyncItems(items []*syncItem, action syncAction, dirs bool) []*syncItem {
+	var filtered []*syncItem
+
+	for _, item := range items {
+		if item.action == action && item.isDir() == dirs {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered
+}
+
+func formatSyncConflicts(conflicts []*syncItem, out io.Writer) {
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "Path\tLocal\tRemote")
+
+	for _, item := range conflicts {
+		local := item.localChange.String()
+		remote := item.remoteChange.String()
+
+		if item.typeMismatch() {
+			local = "file"
+			remote = "file"
+			if item.local.info.IsDir() {
+				local = "directory"
+			} else {
+				remote = "directory"
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", truncateString(item.relPath, 60), local, remote)
+	}
+
+	w.Flush()
+}
+
+// Last synced state of a file, both sides were equal when it was recorded
+type syncBaseFile struct {
+	Md5      string `json:"md5,omitempty"`
+	Size     int64  `json:"size"`
+	Modified int64  `json:"modified"`
+	RemoteId string `json:"remoteId"`
+	Dir      bool   `json:"dir,omitempty"`
+}
+
+type syncBase struct {
+	path  string
+	files map[string]*syncBaseFile
+	mutex *sync.Mutex
+}
+
+func syncBasePath(stateDir, rootId string) string {
+	return filepath.Join(stateDir, fmt.Sprintf("base_%s.json", rootId))
+}
+
+func loadSyncBase(path string) (*syncBase, error) {
+	base := &syncBase{
+		path:  path,
+		files: map[string]*syncBaseFile{},
+		mutex: &sync.Mutex{},
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// First sync of this root
+		return base, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&base.files); err != nil {
+		return nil, err
+	}
+
+	return base, nil
+}
+
+// Records the current state of the item, which must be equal on both sides
+func (self *syncBase) record(item *syncItem) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if item.local == nil && item.remote == nil {
+		delete(self.files, item.relPath)
+		return
+	}
+
+	// Unresolved differences are kept as they were
+	if item.local == nil || item.remote == nil || item.typeMismatch() || item.isDocument() {
+		return
+	}
+
+	self.files[item.relPath] = &syncBaseFile{
+		Md5:      item.remote.Md5(),
+		Size:     item.local.Size(),
+		Modified: item.local.Modified().UnixNano(),
+		RemoteId: item.remote.file.Id,
+		Dir:      item.local.info.IsDir(),
+	}
+}
+
+func (self *syncBase) remove(relPath string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	delete(self.files, relPath)
+}
+
+func (self *syncBase) save() error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(self.path), 0700); err != nil {
+		return err
+	}
+	return utils.WriteJSON(self.path, self.files)
+}
@@ -0,0 +1,236 @@
+package drive
+
+import (
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestDecideFileAction(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      fileSyncState
+		resolution ConflictResolution
+		want       syncAction
+	}{
+		{
+			name:  "unchanged",
+			state: fileSyncState{localChange: syncUnchanged, remoteChange: syncUnchanged, localSize: 5, remoteSize: 5},
+			want:  syncNone,
+		},
+		{
+			name:  "local edit",
+			state: fileSyncState{localChange: syncModified, remoteChange: syncUnchanged, localSize: 6, remoteSize: 5},
+			want:  syncUpload,
+		},
+		{
+			name:  "remote edit",
+			state: fileSyncState{localChange: syncUnchanged, remoteChange: syncModified, localSize: 5, remoteSize: 6},
+			want:  syncDownload,
+		},
+		{
+			name:  "local delete",
+			state: fileSyncState{localChange: syncDeleted, remoteChange: syncUnchanged, localSize: -1, remoteSize: 5},
+			want:  syncDeleteRemote,
+		},
+		{
+			name:  "remote delete",
+			state: fileSyncState{localChange: syncUnchanged, remoteChange: syncDeleted, localSize: 5, remoteSize: -1},
+			want:  syncDeleteLocal,
+		},
+		{
+			name:  "edit/edit",
+			state: fileSyncState{localChange: syncModified, remoteChange: syncModified, localSize: 6, remoteSize: 7},
+			want:  syncConflict,
+		},
+		{
+			name:  "edit/edit with the same content",
+			state: fileSyncState{localChange: syncModified, remoteChange: syncModified, sameContent: true, localSize: 6, remoteSize: 6},
+			want:  syncNone,
+		},
+		{
+			name:       "edit/edit keeping local",
+			state:      fileSyncState{localChange: syncModified, remoteChange: syncModified, localSize: 6, remoteSize: 7},
+			resolution: KeepLocal,
+			want:       syncUpload,
+		},
+		{
+			name:       "edit/edit keeping remote",
+			state:      fileSyncState{localChange: syncModified, remoteChange: syncModified, localSize: 6, remoteSize: 7},
+			resolution: KeepRemote,
+			want:       syncDownload,
+		},
+		{
+			name:       "edit/edit keeping the largest local",
+			state:      fileSyncState{localChange: syncModified, remoteChange: syncModified, localSize: 8, remoteSize: 7},
+			resolution: KeepLargest,
+			want:       syncUpload,
+		},
+		{
+			name:       "edit/edit keeping the largest of equal sizes",
+			state:      fileSyncState{localChange: syncModified, remoteChange: syncModified, localSize: 7, remoteSize: 7},
+			resolution: KeepLargest,
+			want:       syncDownload,
+		},
+		{
+			name:  "delete/edit",
+			state: fileSyncState{localChange: syncDeleted, remoteChange: syncModified, localSize: -1, remoteSize: 7},
+			want:  syncConflict,
+		},
+		{
+			name:       "delete/edit keeping local",
+			state:      fileSyncState{localChange: syncDeleted, remoteChange: syncModified, localSize: -1, remoteSize: 7},
+			resolution: KeepLocal,
+			want:       syncDeleteRemote,
+		},
+		{
+			name:       "delete/edit keeping remote",
+			state:      fileSyncState{localChange: syncDeleted, remoteChange: syncModified, localSize: -1, remoteSize: 7},
+			resolution: KeepRemote,
+			want:       syncDownload,
+		},
+		{
+			name:       "delete/edit keeping the largest",
+			state:      fileSyncState{localChange: syncDeleted, remoteChange: syncModified, localSize: -1, remoteSize: 0},
+			resolution: KeepLargest,
+			want:       syncDownload,
+		},
+		{
+			name:  "edit/delete",
+			state: fileSyncState{localChange: syncModified, remoteChange: syncDeleted, localSize: 6, remoteSize: -1},
+			want:  syncConflict,
+		},
+		{
+			name:       "edit/delete keeping local",
+			state:      fileSyncState{localChange: syncModified, remoteChange: syncDeleted, localSize: 6, remoteSize: -1},
+			resolution: KeepLocal,
+			want:       syncUpload,
+		},
+		{
+			name:       "edit/delete keeping remote",
+			state:      fileSyncState{localChange: syncModified, remoteChange: syncDeleted, localSize: 6, remoteSize: -1},
+			resolution: KeepRemote,
+			want:       syncDeleteLocal,
+		},
+		{
+			name:  "delete/delete",
+			state: fileSyncState{localChange: syncDeleted, remoteChange: syncDeleted, localSize: -1, remoteSize: -1},
+			want:  syncNone,
+		},
+		{
+			name:  "create/create",
+			state: fileSyncState{localChange: syncAdded, remoteChange: syncAdded, localSize: 6, remoteSize: 7},
+			want:  syncConflict,
+		},
+		{
+			name:  "create/create with the same content",
+			state: fileSyncState{localChange: syncAdded, remoteChange: syncAdded, sameContent: true, localSize: 6, remoteSize: 6},
+			want:  syncNone,
+		},
+		{
+			name:  "local create",
+			state: fileSyncState{localChange: syncAdded, remoteChange: syncUnchanged, localSize: 6, remoteSize: -1},
+			want:  syncUpload,
+		},
+		{
+			name:  "remote create",
+			state: fileSyncState{localChange: syncUnchanged, remoteChange: syncAdded, localSize: -1, remoteSize: 6},
+			want:  syncDownload,
+		},
+		{
+			name:       "directory and file",
+			state:      fileSyncState{localChange: syncAdded, remoteChange: syncAdded, typeMismatch: true, localSize: 0, remoteSize: 6},
+			resolution: KeepLocal,
+			want:       syncConflict,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := decideFileAction(test.state, test.resolution); got != test.want {
+				t.Errorf("decideFileAction() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDetectChangesWithoutBase(t *testing.T) {
+	local := &LocalFile{relPath: "a.txt"}
+	remote := &RemoteFile{relPath: "a.txt", file: &drive.File{Id: "id", Md5Checksum: "md5"}}
+
+	tests := []struct {
+		name       string
+		item       *syncItem
+		wantLocal  syncChange
+		wantRemote syncChange
+	}{
+		{
+			name:       "both sides",
+			item:       &syncItem{relPath: "a.txt", local: local, remote: remote},
+			wantLocal:  syncAdded,
+			wantRemote: syncAdded,
+		},
+		{
+			name:       "local only",
+			item:       &syncItem{relPath: "a.txt", local: local},
+			wantLocal:  syncAdded,
+			wantRemote: syncUnchanged,
+		},
+		{
+			name:       "remote only",
+			item:       &syncItem{relPath: "a.txt", remote: remote},
+			wantLocal:  syncUnchanged,
+			wantRemote: syncAdded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.item.detectLocalChange(); got != test.wantLocal {
+				t.Errorf("detectLocalChange() = %s, want %s", got, test.wantLocal)
+			}
+			if got := test.item.detectRemoteChange(); got != test.wantRemote {
+				t.Errorf("detectRemoteChange() = %s, want %s", got, test.wantRemote)
+			}
+		})
+	}
+}
+
+func TestDetectRemoteChange(t *testing.T) {
+	base := &syncBaseFile{Md5: "md5", Size: 5, RemoteId: "id"}
+
+	tests := []struct {
+		name   string
+		remote *RemoteFile
+		want   syncChange
+	}{
+		{
+			name:   "unchanged",
+			remote: &RemoteFile{file: &drive.File{Id: "id", Md5Checksum: "md5"}},
+			want:   syncUnchanged,
+		},
+		{
+			name:   "edited",
+			remote: &RemoteFile{file: &drive.File{Id: "id", Md5Checksum: "other"}},
+			want:   syncModified,
+		},
+		{
+			name:   "replaced by another file",
+			remote: &RemoteFile{file: &drive.File{Id: "other", Md5Checksum: "md5"}},
+			want:   syncModified,
+		},
+		{
+			name: "deleted",
+			want: syncDeleted,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := &syncItem{relPath: "a.txt", remote: test.remote, base: base}
+			if got := item.detectRemoteChange(); got != test.want {
+				t.Errorf("detectRemoteChange() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -2,14 +2,15 @@ package drive
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"time"
 
-	"github.com/imzza/gdrive/internal/utils"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 )
@@ -24,9 +25,25 @@ type DownloadSyncArgs struct {
 	Timeout          time.Duration
 	Resolution       ConflictResolution
 	Comparer         FileComparer
+	Workers          int
+	StateDir         string
+	FullScan         bool
+	Verify           bool
 }
 
 func (self *Drive) DownloadSync(args DownloadSyncArgs) error {
+	if err := self.resolvePaths(&args.RootId); err != nil {
+		return err
+	}
+
+	// Ensure that output lines from concurrent workers are not interleaved
+	args.Out = newSyncWriter(args.Out)
+
+	// Progress bars from several files at once are unreadable, hide them
+	if args.Workers > 1 {
+		args.Progress = io.Discard
+	}
+
 	fmt.Fprintln(args.Out, "Starting sync...")
 	started := time.Now()
 
@@ -37,7 +54,7 @@ func (self *Drive) DownloadSync(args DownloadSyncArgs) error {
 	}
 
 	fmt.Fprintln(args.Out, "Collecting file information...")
-	files, err := self.prepareSyncFiles(args.Path, rootDir, args.Comparer)
+	files, err := self.prepareSyncFiles(args.Path, rootDir, args.Comparer, args.StateDir, args.FullScan, args.Out)
 	if err != nil {
 		return err
 	}
@@ -87,7 +104,7 @@ func (self *Drive) DownloadSync(args DownloadSyncArgs) error {
 
 func (self *Drive) getSyncRoot(rootId string) (*drive.File, error) {
 	fields := []googleapi.Field{"id", "name", "mimeType", "appProperties"}
-	f, err := self.service.Files.Get(rootId).Fields(fields...).Do()
+	f, err := self.service.Files.Get(rootId).SupportsAllDrives(true).Fields(fields...).Do()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to find root dir: %s", err)
 	}
@@ -141,20 +158,16 @@ func (self *Drive) downloadMissingFiles(files *syncFiles, args DownloadSyncArgs)
 		fmt.Fprintf(args.Out, "\n%d local files are missing\n", missingCount)
 	}
 
-	for i, rf := range missingFiles {
+	return runWorkers(args.Workers, missingCount, func(i int) error {
+		rf := missingFiles[i]
 		absPath, err := filepath.Abs(filepath.Join(args.Path, rf.relPath))
 		if err != nil {
 			return fmt.Errorf("Failed to determine local absolute path: %s", err)
 		}
 		fmt.Fprintf(args.Out, "[%04d/%04d] Downloading %s -> %s\n", i+1, missingCount, rf.relPath, filepath.Join(filepath.Base(args.Path), rf.relPath))
 
-		err = self.downloadRemoteFile(rf.file.Id, absPath, args, 0)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+		return self.downloadRemoteFile(rf, absPath, args)
+	})
 }
 
 func (self *Drive) downloadChangedFiles(changedFiles []*changedFile, args DownloadSyncArgs) error {
@@ -164,10 +177,11 @@ func (self *Drive) downloadChangedFiles(changedFiles []*changedFile, args Downlo
 		fmt.Fprintf(args.Out, "\n%d remote files has changed\n", changedCount)
 	}
 
-	for i, cf := range changedFiles {
+	return runWorkers(args.Workers, changedCount, func(i int) error {
+		cf := changedFiles[i]
 		if skip, reason := checkLocalConflict(cf, args.Resolution); skip {
 			fmt.Fprintf(args.Out, "[%04d/%04d] Skipping %s (%s)\n", i+1, changedCount, cf.remote.relPath, reason)
-			continue
+			return nil
 		}
 
 		absPath, err := filepath.Abs(filepath.Join(args.Path, cf.remote.relPath))
@@ -176,78 +190,42 @@ func (self *Drive) downloadChangedFiles(changedFiles []*changedFile, args Downlo
 		}
 		fmt.Fprintf(args.Out, "[%04d/%04d] Downloading %s -> %s\n", i+1, changedCount, cf.remote.relPath, filepath.Join(filepath.Base(args.Path), cf.remote.relPath))
 
-		err = self.downloadRemoteFile(cf.remote.file.Id, absPath, args, 0)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+		return self.downloadRemoteFile(cf.remote, absPath, args)
+	})
 }
 
-func (self *Drive) downloadRemoteFile(id, fpath string, args DownloadSyncArgs, try int) error {
+func (self *Drive) downloadRemoteFile(rf *RemoteFile, fpath string, args DownloadSyncArgs) error {
 	if args.DryRun {
 		return nil
 	}
 
-	// Get timeout reader wrapper and context
-	timeoutReaderWrapper, ctx := utils.GetTimeoutReaderWrapperContext(args.Timeout)
-
-	res, err := self.service.Files.Get(id).Context(ctx).Download()
-	if err != nil {
-		if isBackendOrRateLimitError(err) && try < MaxErrorRetries {
-			exponentialBackoffSleep(try)
-			try++
-			return self.downloadRemoteFile(id, fpath, args, try)
-		} else if isTimeoutError(err) {
-			return fmt.Errorf("Failed to download file: timeout, no data was transferred for %v", args.Timeout)
-		} else {
-			return fmt.Errorf("Failed to download file: %s", err)
-		}
-	}
-
-	// Close body on function exit
-	defer res.Body.Close()
-
-	// Wrap response body in progress reader
-	progressReader := utils.GetProgressReader(res.Body, args.Progress, res.ContentLength)
-
-	// Wrap reader in timeout reader
-	reader := timeoutReaderWrapper(progressReader)
-
-	// Ensure any parent directories exists
-	if err = mkdir(fpath); err != nil {
-		return err
-	}
-
-	// Download to tmp file
-	tmpPath := fpath + ".incomplete"
-
-	// Create new file
-	outFile, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("Unable to create local file: %s", err)
-	}
-
-	// Save file to disk
-	_, err = io.Copy(outFile, reader)
-	if err != nil {
-		outFile.Close()
-		if try < MaxErrorRetries {
-			exponentialBackoffSleep(try)
-			try++
-			return self.downloadRemoteFile(id, fpath, args, try)
-		} else {
-			os.Remove(tmpPath)
-			return fmt.Errorf("Download was interrupted: %s", err)
+	var key *EncryptionKey
+	if isEncrypted(rf.file) {
+		var err error
+		if key, err = self.decryptionKey(rf.file); err != nil {
+			return err
 		}
 	}
 
-	// Close file
-	outFile.Close()
-
-	// Rename tmp file to proper filename
-	return os.Rename(tmpPath, fpath)
+	_, _, err := self.saveFile(saveFileArgs{
+		out: args.Out,
+		download: func(ctx context.Context, offset int64) (*http.Response, error) {
+			call := self.service.Files.Get(rf.file.Id).SupportsAllDrives(true).Context(ctx)
+			setRangeHeader(call.Header(), offset)
+			return call.Download()
+		},
+		md5:        rf.file.Md5Checksum,
+		sha1:       rf.file.Sha1Checksum,
+		sha256:     rf.file.Sha256Checksum,
+		size:       rf.file.Size,
+		fpath:      fpath,
+		force:      true,
+		verify:     args.Verify,
+		decryptKey: key,
+		progress:   args.Progress,
+		timeout:    args.Timeout,
+	})
+	return err
 }
 
 func (self *Drive) deleteExtraneousLocalFiles(files *syncFiles, args DownloadSyncArgs) error {
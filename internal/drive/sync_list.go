@@ -12,6 +12,7 @@ import (
 type ListSyncArgs struct {
 	Out        io.Writer
 	SkipHeader bool
+	Output     OutputFormat
 }
 
 func (self *Drive) ListSync(args ListSyncArgs) error {
@@ -34,9 +35,14 @@ type ListRecursiveSyncArgs struct {
 	PathWidth   int64
 	SizeInBytes bool
 	SortOrder   string
+	Output      OutputFormat
 }
 
 func (self *Drive) ListRecursiveSync(args ListRecursiveSyncArgs) error {
+	if err := self.resolvePaths(&args.RootId); err != nil {
+		return err
+	}
+
 	rootDir, err := self.getSyncRoot(args.RootId)
 	if err != nil {
 		return err
@@ -52,6 +58,19 @@ func (self *Drive) ListRecursiveSync(args ListRecursiveSyncArgs) error {
 }
 
 func printSyncDirectories(files []*drive.File, args ListSyncArgs) {
+	if args.Output != TableOutput {
+		var records []outputRecord
+		for _, f := range files {
+			records = append(records, outputRecord{
+				{"id", f.Id},
+				{"name", f.Name},
+				{"createdTime", f.CreatedTime},
+			})
+		}
+		writeRecords(args.Out, args.Output, args.SkipHeader, records)
+		return
+	}
+
 	w := new(tabwriter.Writer)
 	w.Init(args.Out, 0, 0, 3, ' ', 0)
 
@@ -76,6 +95,23 @@ func printSyncDirContent(files []*RemoteFile, args ListRecursiveSyncArgs) {
 		sort.Sort(byRemotePath(files))
 	}
 
+	if args.Output != TableOutput {
+		var records []outputRecord
+		for _, rf := range files {
+			records = append(records, outputRecord{
+				{"id", rf.file.Id},
+				{"path", rf.relPath},
+				{"type", filetype(rf.file)},
+				{"mimeType", rf.file.MimeType},
+				{"size", rf.file.Size},
+				{"md5Checksum", rf.file.Md5Checksum},
+				{"modifiedTime", rf.file.ModifiedTime},
+			})
+		}
+		writeRecords(args.Out, args.Output, args.SkipHeader, records)
+		return
+	}
+
 	w := new(tabwriter.Writer)
 	w.Init(args.Out, 0, 0, 3, ' ', 0)
 
@@ -0,0 +1,147 @@
+package drive
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/imzza/gdrive/internal/utils"
+	"google.golang.org/api/drive/v3"
+)
+
+// Local copy of the remote files of a sync root. The page token
+// points to the first change that is not reflected in the snapshot
+type remoteSnapshot struct {
+	PageToken string                 `json:"pageToken"`
+	Files     map[string]*drive.File `json:"files"`
+}
+
+func syncSnapshotPath(stateDir, rootId string) string {
+	return filepath.Join(stateDir, fmt.Sprintf("snapshot_%s.json", rootId))
+}
+
+// Prepares remote files from the stored snapshot of the sync root after applying
+// the changes made since the previous sync. A new snapshot is created from a full
+// listing of the remote files if there is no usable snapshot or a full scan is requested
+func (self *Drive) prepareRemoteFilesFromSnapshot(rootDir *drive.File, stateDir string, fullScan bool) ([]*RemoteFile, error) {
+	path := syncSnapshotPath(stateDir, rootDir.Id)
+
+	snapshot, err := loadRemoteSnapshot(path)
+	if err == nil && fullScan {
+		err = fmt.Errorf("Full scan requested")
+	}
+	if err == nil {
+		err = self.updateRemoteSnapshot(rootDir, snapshot)
+	}
+
+	if err != nil {
+		snapshot, err = self.newRemoteSnapshot(rootDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := saveRemoteSnapshot(path, snapshot); err != nil {
+		return nil, fmt.Errorf("Failed to save remote snapshot: %s", err)
+	}
+
+	return self.newRemoteFiles(rootDir, snapshot.list())
+}
+
+func (self *Drive) newRemoteSnapshot(rootDir *drive.File) (*remoteSnapshot, error) {
+	// Get page token before listing so that no changes made during listing are missed
+	pageToken, err := self.GetChangesStartPageToken()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := self.listSyncFiles(rootDir, "")
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &remoteSnapshot{
+		PageToken: pageToken,
+		Files:     map[string]*drive.File{},
+	}
+
+	for _, f := range files {
+		snapshot.Files[f.Id] = f
+	}
+
+	return snapshot, nil
+}
+
+func (self *Drive) updateRemoteSnapshot(rootDir *drive.File, snapshot *remoteSnapshot) error {
+	pageToken := snapshot.PageToken
+
+	for {
+		changeList, err := self.newChangesListCall(pageToken).PageSize(1000).Fields("newStartPageToken", "nextPageToken", "changes(changeType,fileId,removed,file(id,name,parents,md5Checksum,sha1Checksum,sha256Checksum,mimeType,size,modifiedTime,trashed,appProperties))").Do()
+		if err != nil {
+			return fmt.Errorf("Failed listing changes: %s", err)
+		}
+
+		for _, c := range changeList.Changes {
+			if c.ChangeType == "drive" {
+				continue
+			}
+
+			if c.Removed || c.File == nil || c.File.Trashed || c.File.AppProperties["syncRootId"] != rootDir.Id {
+				delete(snapshot.Files, c.FileId)
+				continue
+			}
+
+			snapshot.Files[c.FileId] = c.File
+		}
+
+		if changeList.NewStartPageToken != "" {
+			snapshot.PageToken = changeList.NewStartPageToken
+			return nil
+		}
+
+		pageToken = changeList.NextPageToken
+	}
+}
+
+func (self *remoteSnapshot) list() []*drive.File {
+	var files []*drive.File
+
+	for _, f := range self.Files {
+		files = append(files, f)
+	}
+
+	// Keep order stable between runs
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Id < files[j].Id
+	})
+
+	return files
+}
+
+func loadRemoteSnapshot(path string) (*remoteSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snapshot := &remoteSnapshot{}
+	if err := json.NewDecoder(f).Decode(snapshot); err != nil {
+		return nil, err
+	}
+
+	if snapshot.PageToken == "" || snapshot.Files == nil {
+		return nil, fmt.Errorf("Invalid snapshot")
+	}
+
+	return snapshot, nil
+}
+
+func saveRemoteSnapshot(path string, snapshot *remoteSnapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return utils.WriteJSON(path, snapshot)
+}
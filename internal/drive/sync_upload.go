@@ -21,31 +21,79 @@ type UploadSyncArgs struct {
 	RootId           string
 	DryRun           bool
 	DeleteExtraneous bool
+	Permanent        bool
+	Encrypt          bool
+	EncryptNames     bool
+	Verify           bool
 	ChunkSize        int64
 	Timeout          time.Duration
 	Resolution       ConflictResolution
 	Comparer         FileComparer
+	Workers          int
+	StateDir         string
+	FullScan         bool
+	Sessions         *UploadSessionStore
+	Watch            bool
+	WatchDelay       time.Duration
+	Reconcile        time.Duration
 }
 
 func (self *Drive) UploadSync(args UploadSyncArgs) error {
+	if err := self.resolvePaths(&args.RootId); err != nil {
+		return err
+	}
+
 	if args.ChunkSize > intMax()-1 {
 		return fmt.Errorf("Chunk size is to big, max chunk size for this computer is %d", intMax()-1)
 	}
 
+	// Ensure that output lines from concurrent workers are not interleaved
+	args.Out = newSyncWriter(args.Out)
+
+	// Progress bars from several files at once are unreadable, hide them
+	if args.Workers > 1 {
+		args.Progress = io.Discard
+	}
+
 	fmt.Fprintln(args.Out, "Starting sync...")
 	started := time.Now()
 
 	// Create root directory if it does not exist
-	rootDir, err := self.prepareSyncRoot(args)
+	rootDir, err := self.prepareSyncRoot(args.RootId)
 	if err != nil {
 		return err
 	}
 
-	fmt.Fprintln(args.Out, "Collecting local and remote file information...")
-	files, err := self.prepareSyncFiles(args.Path, rootDir, args.Comparer)
+	// Start watching before the initial sync so that no changes are missed
+	var watcher *fileWatcher
+	if args.Watch {
+		watcher, err = newFileWatcher(args.Path)
+		if err != nil {
+			return err
+		}
+		defer watcher.close()
+	}
+
+	files, err := self.uploadSyncFiles(rootDir, args)
 	if err != nil {
 		return err
 	}
+	fmt.Fprintf(args.Out, "Sync finished in %s\n", time.Since(started))
+
+	if args.Watch {
+		return self.watchUploadSync(watcher, rootDir, files, args)
+	}
+
+	return nil
+}
+
+// Syncs all local files to drive, the returned files reflect the state after the sync
+func (self *Drive) uploadSyncFiles(rootDir *drive.File, args UploadSyncArgs) (*syncFiles, error) {
+	fmt.Fprintln(args.Out, "Collecting local and remote file information...")
+	files, err := self.prepareSyncFiles(args.Path, rootDir, args.Comparer, args.StateDir, args.FullScan, args.Out)
+	if err != nil {
+		return nil, err
+	}
 
 	// Find missing and changed files
 	changedFiles := files.filterChangedLocalFiles()
@@ -55,50 +103,49 @@ func (self *Drive) UploadSync(args UploadSyncArgs) error {
 
 	// Ensure that there is enough free space on drive
 	if ok, msg := self.checkRemoteFreeSpace(missingFiles, changedFiles); !ok {
-		return fmt.Errorf("%s", msg)
+		return nil, fmt.Errorf("%s", msg)
 	}
 
 	// Ensure that we don't overwrite any remote changes
 	if args.Resolution == NoResolution {
 		err = ensureNoRemoteModifications(changedFiles)
 		if err != nil {
-			return fmt.Errorf("Conflict detected!\nThe following files have changed and the remote file are newer than it's local counterpart:\n\n%s\nNo conflict resolution was given, aborting...", err)
+			return nil, fmt.Errorf("Conflict detected!\nThe following files have changed and the remote file are newer than it's local counterpart:\n\n%s\nNo conflict resolution was given, aborting...", err)
 		}
 	}
 
 	// Create missing directories
 	files, err = self.createMissingRemoteDirs(files, args)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Upload missing files
 	err = self.uploadMissingFiles(missingFiles, files, args)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Update modified files
 	err = self.updateChangedFiles(changedFiles, rootDir, args)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Delete extraneous files on drive
 	if args.DeleteExtraneous {
 		err = self.deleteExtraneousRemoteFiles(files, args)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	fmt.Fprintf(args.Out, "Sync finished in %s\n", time.Since(started))
 
-	return nil
+	return files, nil
 }
 
-func (self *Drive) prepareSyncRoot(args UploadSyncArgs) (*drive.File, error) {
+func (self *Drive) prepareSyncRoot(rootId string) (*drive.File, error) {
 	fields := []googleapi.Field{"id", "name", "mimeType", "appProperties"}
-	f, err := self.service.Files.Get(args.RootId).Fields(fields...).Do()
+	f, err := self.service.Files.Get(rootId).SupportsAllDrives(true).Fields(fields...).Do()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to find root dir: %s", err)
 	}
@@ -130,7 +177,7 @@ func (self *Drive) prepareSyncRoot(args UploadSyncArgs) (*drive.File, error) {
 		AppProperties: map[string]string{"sync": "true", "syncRoot": "true"},
 	}
 
-	f, err = self.service.Files.Update(f.Id, dstFile).Fields(fields...).Do()
+	f, err = self.service.Files.Update(f.Id, dstFile).SupportsAllDrives(true).Fields(fields...).Do()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to update root directory: %s", err)
 	}
@@ -159,11 +206,12 @@ func (self *Drive) createMissingRemoteDirs(files *syncFiles, args UploadSyncArgs
 		fmt.Fprintf(args.Out, "[%04d/%04d] Creating directory %s\n", i+1, missingCount, filepath.Join(files.root.file.Name, lf.relPath))
 
 		f, err := self.createMissingRemoteDir(createMissingRemoteDirArgs{
-			name:     lf.info.Name(),
-			parentId: parent.file.Id,
-			rootId:   args.RootId,
-			dryRun:   args.DryRun,
-			try:      0,
+			name:        lf.info.Name(),
+			parentId:    parent.file.Id,
+			rootId:      args.RootId,
+			encryptName: args.Encrypt && args.EncryptNames,
+			dryRun:      args.DryRun,
+			try:         0,
 		})
 		if err != nil {
 			return nil, err
@@ -179,11 +227,12 @@ func (self *Drive) createMissingRemoteDirs(files *syncFiles, args UploadSyncArgs
 }
 
 type createMissingRemoteDirArgs struct {
-	name     string
-	parentId string
-	rootId   string
-	dryRun   bool
-	try      int
+	name        string
+	parentId    string
+	rootId      string
+	encryptName bool
+	dryRun      bool
+	try         int
 }
 
 func (self *Drive) uploadMissingFiles(missingFiles []*LocalFile, files *syncFiles, args UploadSyncArgs) error {
@@ -193,7 +242,8 @@ func (self *Drive) uploadMissingFiles(missingFiles []*LocalFile, files *syncFile
 		fmt.Fprintf(args.Out, "\n%d remote files are missing\n", missingCount)
 	}
 
-	for i, lf := range missingFiles {
+	return runWorkers(args.Workers, missingCount, func(i int) error {
+		lf := missingFiles[i]
 		parentPath := parentFilePath(lf.relPath)
 		parent, ok := files.findRemoteByPath(parentPath)
 		if !ok {
@@ -202,13 +252,15 @@ func (self *Drive) uploadMissingFiles(missingFiles []*LocalFile, files *syncFile
 
 		fmt.Fprintf(args.Out, "[%04d/%04d] Uploading %s -> %s\n", i+1, missingCount, lf.relPath, filepath.Join(files.root.file.Name, lf.relPath))
 
-		err := self.uploadMissingFile(parent.file.Id, lf, args, 0)
-		if err != nil {
+		f, err := self.uploadMissingFile(parent.file.Id, lf, args, 0)
+		if err != nil || f == nil {
 			return err
 		}
-	}
 
-	return nil
+		// Keep track of the new file in case the sync continues in watch mode
+		files.addRemote(&RemoteFile{relPath: lf.relPath, file: f})
+		return nil
+	})
 }
 
 func (self *Drive) updateChangedFiles(changedFiles []*changedFile, root *drive.File, args UploadSyncArgs) error {
@@ -218,21 +270,23 @@ func (self *Drive) updateChangedFiles(changedFiles []*changedFile, root *drive.F
 		fmt.Fprintf(args.Out, "\n%d local files has changed\n", changedCount)
 	}
 
-	for i, cf := range changedFiles {
+	return runWorkers(args.Workers, changedCount, func(i int) error {
+		cf := changedFiles[i]
 		if skip, reason := checkRemoteConflict(cf, args.Resolution); skip {
 			fmt.Fprintf(args.Out, "[%04d/%04d] Skipping %s (%s)\n", i+1, changedCount, cf.local.relPath, reason)
-			continue
+			return nil
 		}
 
 		fmt.Fprintf(args.Out, "[%04d/%04d] Updating %s -> %s\n", i+1, changedCount, cf.local.relPath, filepath.Join(root.Name, cf.local.relPath))
 
-		err := self.updateChangedFile(cf, args, 0)
-		if err != nil {
+		f, err := self.updateChangedFile(cf, args, 0)
+		if err != nil || f == nil {
 			return err
 		}
-	}
 
-	return nil
+		cf.remote.file = f
+		return nil
+	})
 }
 
 func (self *Drive) deleteExtraneousRemoteFiles(files *syncFiles, args UploadSyncArgs) error {
@@ -247,12 +301,16 @@ func (self *Drive) deleteExtraneousRemoteFiles(files *syncFiles, args UploadSync
 	sort.Sort(sort.Reverse(byRemotePathLength(extraneousFiles)))
 
 	for i, rf := range extraneousFiles {
-		fmt.Fprintf(args.Out, "[%04d/%04d] Deleting %s\n", i+1, extraneousCount, filepath.Join(files.root.file.Name, rf.relPath))
+		fmt.Fprintf(args.Out, "[%04d/%04d] %s %s\n", i+1, extraneousCount, remoteDeleteAction(args.Permanent), filepath.Join(files.root.file.Name, rf.relPath))
 
-		err := self.deleteRemoteFile(rf, args, 0)
+		err := self.deleteRemoteFile(rf, args)
 		if err != nil {
 			return err
 		}
+
+		if !args.DryRun {
+			files.removeRemote(rf)
+		}
 	}
 
 	return nil
@@ -270,7 +328,13 @@ func (self *Drive) createMissingRemoteDir(args createMissingRemoteDirArgs) (*dri
 		return dstFile, nil
 	}
 
-	f, err := self.service.Files.Create(dstFile).Do()
+	if args.encryptName {
+		if err := self.setEncryptedName(dstFile); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := self.service.Files.Create(dstFile).SupportsAllDrives(true).Do()
 	if err != nil {
 		if isBackendOrRateLimitError(err) && args.try < MaxErrorRetries {
 			exponentialBackoffSleep(args.try)
@@ -281,17 +345,19 @@ func (self *Drive) createMissingRemoteDir(args createMissingRemoteDirArgs) (*dri
 		}
 	}
 
+	// The sync works with plain names, like those of the listed remote files
+	f.Name = args.name
 	return f, nil
 }
 
-func (self *Drive) uploadMissingFile(parentId string, lf *LocalFile, args UploadSyncArgs, try int) error {
+func (self *Drive) uploadMissingFile(parentId string, lf *LocalFile, args UploadSyncArgs, try int) (*drive.File, error) {
 	if args.DryRun {
-		return nil
+		return nil, nil
 	}
 
 	srcFile, err := os.Open(lf.absPath)
 	if err != nil {
-		return fmt.Errorf("Failed to open file: %s", err)
+		return nil, fmt.Errorf("Failed to open file: %s", err)
 	}
 
 	// Close file on function exit
@@ -304,39 +370,72 @@ func (self *Drive) uploadMissingFile(parentId string, lf *LocalFile, args Upload
 		AppProperties: map[string]string{"sync": "true", "syncRootId": args.RootId},
 	}
 
-	// Chunk size option
-	chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
+	fields := []googleapi.Field{"id", "name", "size", "md5Checksum", "sha1Checksum", "sha256Checksum", "mimeType", "modifiedTime", "appProperties"}
 
-	// Wrap file in progress reader
-	progressReader := utils.GetProgressReader(srcFile, args.Progress, lf.info.Size())
+	checksums := newVerifyChecksums(args.Verify)
 
-	// Wrap reader in timeout reader
-	reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.Timeout)
+	var f *drive.File
 
-	_, err = self.service.Files.Create(dstFile).Fields("id", "name", "size", "md5Checksum").Context(ctx).Media(reader, chunkSize).Do()
+	if args.Encrypt {
+		f, err = self.uploadEncrypted(encryptedUploadArgs{
+			src:         srcFile,
+			size:        lf.info.Size(),
+			file:        dstFile,
+			fields:      fields,
+			encryptName: args.EncryptNames,
+			chunkSize:   args.ChunkSize,
+			progress:    args.Progress,
+			timeout:     args.Timeout,
+			checksums:   checksums,
+		})
+	} else if useResumableUpload(lf.info.Size(), args.ChunkSize) {
+		f, err = self.resumableUpload(resumableUploadArgs{
+			out:       args.Out,
+			src:       srcFile,
+			info:      lf.info,
+			file:      dstFile,
+			fields:    fields,
+			chunkSize: args.ChunkSize,
+			progress:  args.Progress,
+			timeout:   args.Timeout,
+			sessions:  args.Sessions,
+			checksums: checksums,
+		})
+	} else {
+		// Chunk size option
+		chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
+
+		// Wrap file in progress reader
+		progressReader := utils.GetProgressReader(checksumReader(srcFile, checksums), args.Progress, lf.info.Size())
+
+		// Wrap reader in timeout reader
+		reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.Timeout)
+
+		f, err = self.service.Files.Create(dstFile).SupportsAllDrives(true).Fields(fields...).Context(ctx).Media(reader, chunkSize).Do()
+	}
 	if err != nil {
 		if isBackendOrRateLimitError(err) && try < MaxErrorRetries {
 			exponentialBackoffSleep(try)
 			try++
 			return self.uploadMissingFile(parentId, lf, args, try)
 		} else if isTimeoutError(err) {
-			return fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
+			return nil, fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
 		} else {
-			return fmt.Errorf("Failed to upload file: %s", err)
+			return nil, fmt.Errorf("Failed to upload file: %s", err)
 		}
 	}
 
-	return nil
+	return f, verifyUpload(checksums, f)
 }
 
-func (self *Drive) updateChangedFile(cf *changedFile, args UploadSyncArgs, try int) error {
+func (self *Drive) updateChangedFile(cf *changedFile, args UploadSyncArgs, try int) (*drive.File, error) {
 	if args.DryRun {
-		return nil
+		return nil, nil
 	}
 
 	srcFile, err := os.Open(cf.local.absPath)
 	if err != nil {
-		return fmt.Errorf("Failed to open file: %s", err)
+		return nil, fmt.Errorf("Failed to open file: %s", err)
 	}
 
 	// Close file on function exit
@@ -344,54 +443,89 @@ func (self *Drive) updateChangedFile(cf *changedFile, args UploadSyncArgs, try i
 
 	// Instantiate drive file
 	dstFile := &drive.File{}
+	if !args.Encrypt {
+		clearContentEncryption(dstFile)
+	}
 
-	// Chunk size option
-	chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
+	fields := []googleapi.Field{"id", "name", "size", "md5Checksum", "sha1Checksum", "sha256Checksum", "mimeType", "modifiedTime", "appProperties"}
 
-	// Wrap file in progress reader
-	progressReader := utils.GetProgressReader(srcFile, args.Progress, cf.local.info.Size())
+	checksums := newVerifyChecksums(args.Verify)
 
-	// Wrap reader in timeout reader
-	reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.Timeout)
+	var f *drive.File
 
-	_, err = self.service.Files.Update(cf.remote.file.Id, dstFile).Context(ctx).Media(reader, chunkSize).Do()
+	if args.Encrypt {
+		// The name is left as it is
+		f, err = self.uploadEncrypted(encryptedUploadArgs{
+			src:       srcFile,
+			size:      cf.local.info.Size(),
+			file:      dstFile,
+			fileId:    cf.remote.file.Id,
+			fields:    fields,
+			chunkSize: args.ChunkSize,
+			progress:  args.Progress,
+			timeout:   args.Timeout,
+			checksums: checksums,
+		})
+	} else if useResumableUpload(cf.local.info.Size(), args.ChunkSize) {
+		f, err = self.resumableUpload(resumableUploadArgs{
+			out:       args.Out,
+			src:       srcFile,
+			info:      cf.local.info,
+			file:      dstFile,
+			fileId:    cf.remote.file.Id,
+			fields:    fields,
+			chunkSize: args.ChunkSize,
+			progress:  args.Progress,
+			timeout:   args.Timeout,
+			sessions:  args.Sessions,
+			checksums: checksums,
+		})
+	} else {
+		// Chunk size option
+		chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
+
+		// Wrap file in progress reader
+		progressReader := utils.GetProgressReader(checksumReader(srcFile, checksums), args.Progress, cf.local.info.Size())
+
+		// Wrap reader in timeout reader
+		reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.Timeout)
+
+		f, err = self.service.Files.Update(cf.remote.file.Id, dstFile).SupportsAllDrives(true).Fields(fields...).Context(ctx).Media(reader, chunkSize).Do()
+	}
 	if err != nil {
 		if isBackendOrRateLimitError(err) && try < MaxErrorRetries {
 			exponentialBackoffSleep(try)
 			try++
 			return self.updateChangedFile(cf, args, try)
 		} else if isTimeoutError(err) {
-			return fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
+			return nil, fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
 		} else {
-			return fmt.Errorf("Failed to update file: %s", err)
+			return nil, fmt.Errorf("Failed to update file: %s", err)
 		}
 	}
 
-	return nil
+	return f, verifyUpload(checksums, f)
 }
 
-func (self *Drive) deleteRemoteFile(rf *RemoteFile, args UploadSyncArgs, try int) error {
+func (self *Drive) deleteRemoteFile(rf *RemoteFile, args UploadSyncArgs) error {
 	if args.DryRun {
 		return nil
 	}
 
-	err := self.service.Files.Delete(rf.file.Id).Do()
-	if err != nil {
-		if isBackendOrRateLimitError(err) && try < MaxErrorRetries {
-			exponentialBackoffSleep(try)
-			try++
-			return self.deleteRemoteFile(rf, args, try)
-		} else {
-			return fmt.Errorf("Failed to delete file: %s", err)
-		}
-	}
+	return self.deleteFile(rf.file.Id, args.Permanent)
+}
 
-	return nil
+// Describes what happens to remote files that are removed by a sync
+func remoteDeleteAction(permanent bool) string {
+	if permanent {
+		return "Deleting"
+	}
+	return "Trashing"
 }
 
 func (self *Drive) dirIsEmpty(id string) (bool, error) {
-	query := fmt.Sprintf("'%s' in parents", id)
-	fileList, err := self.service.Files.List().Q(query).Do()
+	query := fmt.Sprintf("'%s' in parents and trashed = false", id)
+	fileList, err := self.newFilesListCall().Q(query).Do()
 	if err != nil {
 		return false, fmt.Errorf("Empty dir check failed: %s", err)
 	}
@@ -452,6 +586,11 @@ func ensureNoRemoteModifications(files []*changedFile) error {
 }
 
 func (self *Drive) checkRemoteFreeSpace(missingFiles []*LocalFile, changedFiles []*changedFile) (bool, string) {
+	// Shared drives do not count against the users storage quota
+	if self.driveId != "" {
+		return true, ""
+	}
+
 	about, err := self.service.About.Get().Fields("storageQuota").Do()
 	if err != nil {
 		return false, fmt.Sprintf("Failed to determine free space: %s", err)
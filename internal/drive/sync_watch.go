@@ -0,0 +1,238 @@
+package drive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Watches a sync directory for changes. The paths of changed files are sent on
+// events relative to the sync root, the path "." means that events were lost
+// and that the whole directory has to be synced again
+type fileWatcher struct {
+	events chan string
+	errors chan error
+	close  func() error
+}
+
+func (self *Drive) watchUploadSync(watcher *fileWatcher, rootDir *drive.File, files *syncFiles, args UploadSyncArgs) error {
+	fmt.Fprintf(args.Out, "\nWatching %s for changes...\n", args.Path)
+
+	pending := map[string]bool{}
+
+	// Changed paths are collected until no new events have arrived for the watch delay
+	delay := time.NewTimer(args.WatchDelay)
+	delay.Stop()
+
+	// Missed events are caught by a periodic full sync
+	var reconcile <-chan time.Time
+	if args.Reconcile > 0 {
+		ticker := time.NewTicker(args.Reconcile)
+		defer ticker.Stop()
+		reconcile = ticker.C
+	}
+
+	// The remote snapshot makes the full syncs cheap, there is no need for a full scan again
+	args.FullScan = false
+
+	fullSync := func() {
+		started := time.Now()
+
+		f, err := self.uploadSyncFiles(rootDir, args)
+		if err != nil {
+			fmt.Fprintf(args.Out, "Sync failed: %s\n", err)
+			return
+		}
+
+		files = f
+		fmt.Fprintf(args.Out, "Sync finished in %s\n", time.Since(started))
+	}
+
+	for {
+		select {
+		case relPath := <-watcher.events:
+			pending[relPath] = true
+			delay.Reset(args.WatchDelay)
+
+		case err := <-watcher.errors:
+			return fmt.Errorf("Failed to watch %s: %s", args.Path, err)
+
+		case <-delay.C:
+			if pending["."] {
+				fmt.Fprintln(args.Out, "\nLost track of local changes, syncing all files...")
+				fullSync()
+			} else {
+				err := self.uploadWatchedPaths(pending, files, args)
+				if err != nil {
+					fmt.Fprintf(args.Out, "Sync failed: %s\n", err)
+				}
+			}
+			pending = map[string]bool{}
+
+		case <-reconcile:
+			fmt.Fprintln(args.Out, "\nSyncing all files...")
+			fullSync()
+			pending = map[string]bool{}
+		}
+	}
+}
+
+// Syncs the given paths and everything below them, files holds the remote
+// files and is kept up to date with the changes made
+func (self *Drive) uploadWatchedPaths(pending map[string]bool, files *syncFiles, args UploadSyncArgs) error {
+	var relPaths []string
+	for relPath := range pending {
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Strings(relPaths)
+
+	fmt.Fprintf(args.Out, "\nSyncing %d changed paths...\n", len(relPaths))
+
+	absRootPath, err := filepath.Abs(args.Path)
+	if err != nil {
+		return err
+	}
+
+	shouldIgnore, err := prepareIgnorer(filepath.Join(absRootPath, DefaultIgnoreFile))
+	if err != nil {
+		return err
+	}
+
+	var local []*LocalFile
+	var deleted []string
+	seen := map[string]bool{}
+
+	for _, relPath := range relPaths {
+		absPath := filepath.Join(absRootPath, relPath)
+
+		if _, err := os.Lstat(absPath); os.IsNotExist(err) {
+			deleted = append(deleted, relPath)
+			continue
+		}
+
+		lfs, err := walkLocalFiles(absRootPath, absPath, shouldIgnore, args.Out)
+		if err != nil {
+			return err
+		}
+
+		// A path may be below another changed directory
+		for _, lf := range lfs {
+			if !seen[lf.relPath] {
+				seen[lf.relPath] = true
+				local = append(local, lf)
+			}
+		}
+	}
+
+	// Use the known remote files with only the changed local files
+	changes := &syncFiles{
+		root:    files.root,
+		local:   local,
+		remote:  files.remote,
+		compare: files.compare,
+		mutex:   files.mutex,
+	}
+
+	changedFiles := changes.filterChangedLocalFiles()
+	missingFiles := changes.filterMissingRemoteFiles()
+
+	// Conflicting files are skipped without stopping the watch
+	if args.Resolution == NoResolution {
+		if err := ensureNoRemoteModifications(changedFiles); err != nil {
+			fmt.Fprintf(args.Out, "Conflict detected!\nThe following files have changed and the remote file are newer than it's local counterpart:\n\n%s\nNo conflict resolution was given, skipping...\n", err)
+			changedFiles = withoutConflicts(changedFiles, findRemoteConflicts(changedFiles))
+		}
+	}
+
+	err = self.uploadWatchedChanges(changes, missingFiles, changedFiles, args)
+
+	// Files created before a failure must be known to the next event, or they would be created again
+	files.remote = changes.remote
+
+	if err != nil {
+		return err
+	}
+
+	if args.DeleteExtraneous {
+		return self.deleteWatchedRemoteFiles(deleted, files, args)
+	}
+
+	return nil
+}
+
+func (self *Drive) uploadWatchedChanges(changes *syncFiles, missingFiles []*LocalFile, changedFiles []*changedFile, args UploadSyncArgs) error {
+	_, err := self.createMissingRemoteDirs(changes, args)
+	if err != nil {
+		return err
+	}
+
+	err = self.uploadMissingFiles(missingFiles, changes, args)
+	if err != nil {
+		return err
+	}
+
+	return self.updateChangedFiles(changedFiles, changes.root.file, args)
+}
+
+// Deletes the remote counterparts of deleted local paths and everything below them
+func (self *Drive) deleteWatchedRemoteFiles(deleted []string, files *syncFiles, args UploadSyncArgs) error {
+	var extraneousFiles []*RemoteFile
+
+	for _, rf := range files.remote {
+		for _, relPath := range deleted {
+			if rf.relPath == relPath || strings.HasPrefix(rf.relPath, relPath+string(filepath.Separator)) {
+				extraneousFiles = append(extraneousFiles, rf)
+				break
+			}
+		}
+	}
+
+	extraneousCount := len(extraneousFiles)
+
+	if extraneousCount > 0 {
+		fmt.Fprintf(args.Out, "\n%d remote files are extraneous\n", extraneousCount)
+	}
+
+	// Sort files so that the files with the longest path comes first
+	sort.Sort(sort.Reverse(byRemotePathLength(extraneousFiles)))
+
+	for i, rf := range extraneousFiles {
+		fmt.Fprintf(args.Out, "[%04d/%04d] %s %s\n", i+1, extraneousCount, remoteDeleteAction(args.Permanent), filepath.Join(files.root.file.Name, rf.relPath))
+
+		err := self.deleteRemoteFile(rf, args)
+		if err != nil {
+			return err
+		}
+
+		if !args.DryRun {
+			files.removeRemote(rf)
+		}
+	}
+
+	return nil
+}
+
+func withoutConflicts(files []*changedFile, conflicts []*changedFile) []*changedFile {
+	var filtered []*changedFile
+
+	for _, cf := range files {
+		conflict := false
+		for _, c := range conflicts {
+			if c == cf {
+				conflict = true
+				break
+			}
+		}
+
+		if !conflict {
+			filtered = append(filtered, cf)
+		}
+	}
+
+	return filtered
+}
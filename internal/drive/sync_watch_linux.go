@@ -0,0 +1,209 @@
+//go:build linux
+
+package drive
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+// Watches the directory tree at path with inotify, files ignored by .gdriveignore are not reported
+func newFileWatcher(path string) (*fileWatcher, error) {
+	absRootPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	shouldIgnore, err := prepareIgnorer(filepath.Join(absRootPath, DefaultIgnoreFile))
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to initialize inotify: %s", err)
+	}
+
+	w := &inotifyWatcher{
+		fd:           fd,
+		root:         absRootPath,
+		shouldIgnore: shouldIgnore,
+		watches:      map[int]string{},
+		mutex:        &sync.Mutex{},
+		events:       make(chan string, 1024),
+		errors:       make(chan error, 1),
+	}
+
+	if err := w.addRecursive("."); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	go w.readEvents()
+
+	return &fileWatcher{
+		events: w.events,
+		errors: w.errors,
+		close:  w.close,
+	}, nil
+}
+
+type inotifyWatcher struct {
+	fd           int
+	root         string
+	shouldIgnore ignoreFunc
+	watches      map[int]string
+	mutex        *sync.Mutex
+	events       chan string
+	errors       chan error
+}
+
+// Adds a watch for the directory at relPath and all directories below it
+func (self *inotifyWatcher) addRecursive(relPath string) error {
+	return filepath.Walk(filepath.Join(self.root, relPath), func(absPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			// The directory may have been removed again before we got to it
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(self.root, absPath)
+		if err != nil {
+			return err
+		}
+
+		if rel != "." && self.shouldIgnore(rel) {
+			return filepath.SkipDir
+		}
+
+		wd, err := syscall.InotifyAddWatch(self.fd, absPath, inotifyMask)
+		if err != nil {
+			return fmt.Errorf("Failed to watch %s: %s", absPath, err)
+		}
+
+		self.mutex.Lock()
+		self.watches[wd] = rel
+		self.mutex.Unlock()
+
+		return nil
+	})
+}
+
+// Removes the watches of the directory at relPath and all directories below it
+func (self *inotifyWatcher) removeRecursive(relPath string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for wd, rel := range self.watches {
+		if rel == relPath || strings.HasPrefix(rel, relPath+string(filepath.Separator)) {
+			syscall.InotifyRmWatch(self.fd, uint32(wd))
+			delete(self.watches, wd)
+		}
+	}
+}
+
+func (self *inotifyWatcher) readEvents() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		n, err := syscall.Read(self.fd, buf)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			self.errors <- err
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			name := string(bytes.TrimRight(nameBytes, "\x00"))
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			if err := self.handleEvent(event, name); err != nil {
+				self.errors <- err
+				return
+			}
+		}
+	}
+}
+
+func (self *inotifyWatcher) handleEvent(event *syscall.InotifyEvent, name string) error {
+	// The kernel queue overflowed and events were lost
+	if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+		self.events <- "."
+		return nil
+	}
+
+	self.mutex.Lock()
+	dir, found := self.watches[int(event.Wd)]
+	if event.Mask&syscall.IN_IGNORED != 0 {
+		delete(self.watches, int(event.Wd))
+	}
+	self.mutex.Unlock()
+
+	if !found || name == "" {
+		return nil
+	}
+
+	relPath := filepath.Join(dir, name)
+	if self.shouldIgnore(relPath) || isIncompleteDownload(filepath.Join(self.root, relPath)) {
+		return nil
+	}
+
+	isDir := event.Mask&syscall.IN_ISDIR != 0
+
+	switch {
+	case isDir && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		// Files may have been added to the directory before the watch was in place,
+		// they are found when the whole directory is synced
+		if err := self.addRecursive(relPath); err != nil {
+			return err
+		}
+	case isDir && event.Mask&syscall.IN_MOVED_FROM != 0:
+		self.removeRecursive(relPath)
+	case !isDir && event.Mask&syscall.IN_CREATE != 0 && self.isBeingWritten(relPath):
+		// New files are synced when they are closed after writing
+		return nil
+	}
+
+	self.events <- relPath
+	return nil
+}
+
+// Reports whether a new file was created by opening it for writing, which is
+// followed by a close event. Symlinks, hard links and other files that are
+// created without being opened never get one
+func (self *inotifyWatcher) isBeingWritten(relPath string) bool {
+	info, err := os.Lstat(filepath.Join(self.root, relPath))
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	// A new hard link shares the inode of an existing file
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Nlink > 1 {
+		return false
+	}
+
+	return true
+}
+
+func (self *inotifyWatcher) close() error {
+	return syscall.Close(self.fd)
+}
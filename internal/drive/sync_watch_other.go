@@ -0,0 +1,11 @@
+//go:build !linux
+
+package drive
+
+import (
+	"fmt"
+)
+
+func newFileWatcher(path string) (*fileWatcher, error) {
+	return nil, fmt.Errorf("Watching for changes is only supported on linux")
+}
@@ -0,0 +1,411 @@
+package drive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imzza/gdrive/internal/utils"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// Resumable upload session of a local file, persisted so
+// that an interrupted upload can be continued by a later run
+type UploadSession struct {
+	Uri      string `json:"uri"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Modified int64  `json:"modified"`
+}
+
+func NewUploadSessionStore(path string) *UploadSessionStore {
+	sessions := map[string]*UploadSession{}
+
+	f, err := os.Open(path)
+	if err == nil {
+		json.NewDecoder(f).Decode(&sessions)
+		f.Close()
+	}
+
+	return &UploadSessionStore{
+		path:     path,
+		sessions: sessions,
+		mutex:    &sync.Mutex{},
+	}
+}
+
+type UploadSessionStore struct {
+	path     string
+	sessions map[string]*UploadSession
+	mutex    *sync.Mutex
+}
+
+// Returns the stored session for key if the local file has not changed since the session was started
+func (self *UploadSessionStore) get(key string, info os.FileInfo) (*UploadSession, bool) {
+	if self == nil {
+		return nil, false
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	session, found := self.sessions[key]
+	if !found || session.Size != info.Size() || session.Modified != info.ModTime().UnixNano() {
+		return nil, false
+	}
+
+	return session, true
+}
+
+func (self *UploadSessionStore) put(key string, session *UploadSession) error {
+	if self == nil {
+		return nil
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.sessions[key] = session
+	return self.save()
+}
+
+func (self *UploadSessionStore) remove(key string) error {
+	if self == nil {
+		return nil
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if _, found := self.sessions[key]; !found {
+		return nil
+	}
+
+	delete(self.sessions, key)
+	return self.save()
+}
+
+// Session uris allow uploading without further authorization,
+// so the file is only readable by the user
+func (self *UploadSessionStore) save() error {
+	if err := utils.WriteJSON(self.path, self.sessions); err != nil {
+		return fmt.Errorf("Failed to save upload sessions: %s", err)
+	}
+
+	if err := os.Chmod(self.path, 0600); err != nil {
+		return fmt.Errorf("Failed to save upload sessions: %s", err)
+	}
+
+	return nil
+}
+
+// The upload goes on when the sessions can not be saved,
+// it can only not be resumed by a later run
+func reportSessionError(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+	}
+}
+
+// Files larger than one chunk are uploaded with our own resumable upload
+// implementation so that the session can be persisted and resumed
+func useResumableUpload(size int64, chunkSize int64) bool {
+	return chunkSize > 0 && size > chunkSize
+}
+
+type resumableUploadArgs struct {
+	out       io.Writer
+	src       *os.File
+	info      os.FileInfo
+	file      *drive.File
+	fileId    string
+	fields    []googleapi.Field
+	chunkSize int64
+	progress  io.Writer
+	timeout   time.Duration
+	sessions  *UploadSessionStore
+	// The uploaded content is hashed into checksums unless it is nil
+	checksums *checksumWriter
+}
+
+// Identifies the upload target, a session is only resumed by a new upload of the same file to the same target
+func (self resumableUploadArgs) sessionKey() string {
+	absPath, err := filepath.Abs(self.src.Name())
+	if err != nil {
+		absPath = self.src.Name()
+	}
+
+	if self.fileId != "" {
+		return fmt.Sprintf("%s|update|%s", absPath, self.fileId)
+	}
+
+	return fmt.Sprintf("%s|create|%s|%s", absPath, strings.Join(self.file.Parents, ","), self.file.Name)
+}
+
+func (self *Drive) resumableUpload(args resumableUploadArgs) (*drive.File, error) {
+	key := args.sessionKey()
+	size := args.info.Size()
+
+	var offset int64
+
+	session, found := args.sessions.get(key, args.info)
+	if found {
+		var f *drive.File
+		var err error
+
+		offset, f, err = self.queryUploadSession(session.Uri, size)
+		if err == nil && f != nil {
+			// Upload was completed by a previous run
+			reportSessionError(args.sessions.remove(key))
+			return f, self.hashUploaded(args, size)
+		}
+
+		if err == nil {
+			fmt.Fprintf(args.out, "Resuming upload of %s from %s\n", args.info.Name(), formatSize(offset, false))
+		} else {
+			// Session has expired or is otherwise unusable, start over
+			reportSessionError(args.sessions.remove(key))
+			found = false
+			offset = 0
+		}
+	}
+
+	if err := self.hashUploaded(args, offset); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		uri, err := self.startUploadSession(args)
+		if err != nil {
+			return nil, err
+		}
+
+		session = &UploadSession{
+			Uri:      uri,
+			Path:     args.src.Name(),
+			Size:     size,
+			Modified: args.info.ModTime().UnixNano(),
+		}
+		reportSessionError(args.sessions.put(key, session))
+	}
+
+	try := 0
+
+	for {
+		f, err := self.uploadSessionChunks(session.Uri, offset, args)
+		if err == nil {
+			reportSessionError(args.sessions.remove(key))
+			return f, nil
+		}
+
+		// The session is kept so that the upload can be resumed by a later run
+		if try >= MaxErrorRetries || !isRetryableTransferError(err) {
+			return nil, err
+		}
+
+		exponentialBackoffSleep(try)
+		try++
+
+		// Ask the server how much it has received
+		offset, f, err = self.queryUploadSession(session.Uri, size)
+		if err != nil {
+			return nil, err
+		}
+
+		if f != nil {
+			reportSessionError(args.sessions.remove(key))
+			return f, nil
+		}
+	}
+}
+
+// Hashes the part of the file that was uploaded by a previous run
+func (self *Drive) hashUploaded(args resumableUploadArgs, n int64) error {
+	if args.checksums == nil || n == 0 {
+		return nil
+	}
+	return args.checksums.hashFile(args.src.Name(), n)
+}
+
+func (self *Drive) startUploadSession(args resumableUploadArgs) (string, error) {
+	body, err := json.Marshal(args.file)
+	if err != nil {
+		return "", err
+	}
+
+	method := "POST"
+	urls := googleapi.ResolveRelative(self.service.BasePath, "/upload/drive/v3/files")
+	if args.fileId != "" {
+		method = "PATCH"
+		urls += "/" + url.PathEscape(args.fileId)
+	}
+
+	params := url.Values{}
+	params.Set("uploadType", "resumable")
+	params.Set("supportsAllDrives", "true")
+	params.Set("fields", googleapi.CombineFields(args.fields))
+
+	req, err := http.NewRequest(method, urls+"?"+params.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(args.info.Size(), 10))
+	if args.file.MimeType != "" {
+		req.Header.Set("X-Upload-Content-Type", args.file.MimeType)
+	}
+
+	res, err := self.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if err := googleapi.CheckResponse(res); err != nil {
+		return "", err
+	}
+
+	uri := res.Header.Get("Location")
+	if uri == "" {
+		return "", fmt.Errorf("Upload session was not created")
+	}
+
+	return uri, nil
+}
+
+// Returns the offset of the next byte the server expects,
+// or the uploaded file if the server already has received all bytes
+func (self *Drive) queryUploadSession(uri string, size int64) (int64, *drive.File, error) {
+	req, err := http.NewRequest("PUT", uri, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req.ContentLength = 0
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+
+	res, err := self.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return parseUploadResponse(res)
+}
+
+// Uploads the content of the local file from offset to the end in chunks
+func (self *Drive) uploadSessionChunks(uri string, offset int64, args resumableUploadArgs) (*drive.File, error) {
+	size := args.info.Size()
+
+	if _, err := args.src.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Wrap file in progress reader
+	progressReader := utils.GetProgressReader(args.src, args.progress, size-offset)
+
+	// Wrap reader in timeout reader
+	reader, ctx := utils.GetTimeoutReaderContext(progressReader, args.timeout)
+
+	for {
+		n := min64(args.chunkSize, size-offset)
+
+		var chunk io.Reader = io.LimitReader(reader, n)
+		if args.checksums != nil {
+			chunk = io.TeeReader(chunk, args.checksums.at(offset))
+		}
+
+		f, next, err := self.uploadSessionChunk(ctx, uri, chunk, offset, n, size)
+		if err != nil {
+			return nil, err
+		}
+
+		if f != nil {
+			// Read to end of file to finish progress output and stop the timeout timer
+			io.Copy(io.Discard, reader)
+			return f, nil
+		}
+
+		// The server may have received fewer bytes than we sent
+		if next != offset+n {
+			if _, err := args.src.Seek(next, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+
+		offset = next
+	}
+}
+
+func (self *Drive) uploadSessionChunk(ctx context.Context, uri string, r io.Reader, offset, n, size int64) (*drive.File, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "PUT", uri, r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.ContentLength = n
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+n-1, size))
+
+	res, err := self.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	next, f, err := parseUploadResponse(res)
+	return f, next, err
+}
+
+// Parses a response from an upload session. An incomplete upload is answered with
+// status 308 and the range of bytes received, a complete upload with the file
+func parseUploadResponse(res *http.Response) (int64, *drive.File, error) {
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusPermanentRedirect {
+		// Range is on the form 'bytes=0-<last byte>', no range means that nothing has been received
+		rangeHeader := res.Header.Get("Range")
+		if rangeHeader == "" {
+			return 0, nil, nil
+		}
+
+		i := strings.LastIndex(rangeHeader, "-")
+		if i < 0 {
+			return 0, nil, fmt.Errorf("Invalid range in upload response: %s", rangeHeader)
+		}
+
+		last, err := strconv.ParseInt(rangeHeader[i+1:], 10, 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("Invalid range in upload response: %s", rangeHeader)
+		}
+
+		return last + 1, nil, nil
+	}
+
+	if err := googleapi.CheckResponse(res); err != nil {
+		return 0, nil, err
+	}
+
+	f := &drive.File{}
+	if err := json.NewDecoder(res.Body).Decode(f); err != nil {
+		return 0, nil, fmt.Errorf("Failed to decode upload response: %s", err)
+	}
+
+	return f.Size, f, nil
+}
+
+func min64(x int64, y int64) int64 {
+	if x < y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,81 @@
+package drive
+
+import (
+	"io"
+	"sync"
+)
+
+// Wraps writer so that lines written by concurrent workers are not interleaved
+func newSyncWriter(w io.Writer) io.Writer {
+	if _, ok := w.(*syncWriter); ok {
+		return w
+	}
+
+	return &syncWriter{
+		writer: w,
+		mutex:  &sync.Mutex{},
+	}
+}
+
+type syncWriter struct {
+	writer io.Writer
+	mutex  *sync.Mutex
+}
+
+func (self *syncWriter) Write(p []byte) (int, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.writer.Write(p)
+}
+
+// Calls fn for every index in [0, count) using the given number of concurrent workers.
+// No new jobs are started after the first error, which is returned when all
+// running jobs have finished
+func runWorkers(workers int, count int, fn func(int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	wg := &sync.WaitGroup{}
+	mutex := &sync.Mutex{}
+
+	var firstErr error
+
+	failed := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return firstErr != nil
+	}
+
+	for n := 0; n < min(workers, count); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				// Drain remaining jobs without running them if another worker failed
+				if failed() {
+					continue
+				}
+
+				if err := fn(i); err != nil {
+					mutex.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < count && !failed(); i++ {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return firstErr
+}
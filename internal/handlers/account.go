@@ -246,7 +246,7 @@ func addAccountWithName(baseDir, name string, secret utils.AccountSecret, args c
 	}
 
 	client := accountAuthClient(args, accountPath, secret)
-	drv, err := drive.New(client)
+	drv, err := drive.New(client, "")
 	if err != nil {
 		utils.ExitF("Failed to create drive client: %s", err)
 	}
@@ -286,7 +286,7 @@ func addAccountWithEmail(baseDir string, secret utils.AccountSecret, args cli.Ar
 	defer os.RemoveAll(tmpDir)
 
 	client := accountAuthClient(args, tmpDir, secret)
-	drv, err := drive.New(client)
+	drv, err := drive.New(client, "")
 	if err != nil {
 		utils.ExitF("Failed to create drive client: %s", err)
 	}
@@ -18,6 +18,10 @@ const ClientId = "367116221053-7n0vf5akeru7on6o2fjinrecpdoe99eg.apps.googleuserc
 const ClientSecret = "1qsNodXNaWq1mQuBjUjmvhoO"
 const TokenFilename = "tokens.json"
 const DefaultCacheFileName = "file_cache.json"
+const DefaultUploadSessionsFileName = "upload_sessions.json"
+const DefaultSyncStateDirName = "sync"
+const DefaultChangesStateFileName = "changes_state.json"
+const DefaultEncryptionSaltFileName = "encryption_salt"
 
 func ListHandler(ctx cli.Context) {
 	args := ctx.Args()
@@ -25,11 +29,13 @@ func ListHandler(ctx cli.Context) {
 		Out:         os.Stdout,
 		MaxFiles:    args.Int64("maxFiles"),
 		NameWidth:   args.Int64("nameWidth"),
-		Query:       args.String("query"),
+		Query:       fileQuery(args),
 		SortOrder:   args.String("sortOrder"),
 		SkipHeader:  args.Bool("skipHeader"),
 		SizeInBytes: args.Bool("sizeInBytes"),
 		AbsPath:     args.Bool("absPath"),
+		Output:      outputFormat(args),
+		Format:      args.String("format"),
 	})
 	utils.CheckErr(err)
 }
@@ -57,15 +63,24 @@ func MoveHandler(ctx cli.Context) {
 func CopyHandler(ctx cli.Context) {
 	args := ctx.Args()
 	err := newDrive(args).Copy(drive.CopyArgs{
-		Out:      os.Stdout,
-		Id:       args.String("fileId"),
-		FolderId: args.String("folderId"),
+		Out:        os.Stdout,
+		Id:         args.String("fileId"),
+		FolderId:   args.String("folderId"),
+		Recursive:  args.Bool("recursive"),
+		Preserve:   args.Bool("preserve"),
+		SkipHeader: args.Bool("skipHeader"),
+		Output:     outputFormat(args),
 	})
 	utils.CheckErr(err)
 }
 
 func ListChangesHandler(ctx cli.Context) {
 	args := ctx.Args()
+
+	if args.Bool("follow") && args.Int64("interval") < 1 {
+		utils.ExitF("The interval must be at least 1 second")
+	}
+
 	err := newDrive(args).ListChanges(drive.ListChangesArgs{
 		Out:        os.Stdout,
 		PageToken:  args.String("pageToken"),
@@ -73,6 +88,12 @@ func ListChangesHandler(ctx cli.Context) {
 		Now:        args.Bool("now"),
 		NameWidth:  args.Int64("nameWidth"),
 		SkipHeader: args.Bool("skipHeader"),
+		Follow:     args.Bool("follow"),
+		Interval:   durationInSeconds(args.Int64("interval")),
+		StateFile:  changesStateFile(args),
+		Command:    args.String("command"),
+		Output:     outputFormat(args),
+		Format:     args.String("format"),
 	})
 	utils.CheckErr(err)
 }
@@ -81,17 +102,23 @@ func DownloadHandler(ctx cli.Context) {
 	args := ctx.Args()
 	checkDownloadArgs(args)
 	err := newDrive(args).Download(drive.DownloadArgs{
-		Out:       os.Stdout,
-		Id:        args.String("fileId"),
-		Force:     args.Bool("force"),
-		Skip:      args.Bool("skip"),
-		Path:      args.String("path"),
-		Delete:    args.Bool("delete"),
-		Recursive: args.Bool("recursive"),
-		NoParent:  args.Bool("noParent"),
-		Stdout:    args.Bool("stdout"),
-		Progress:  progressWriter(args.Bool("noProgress")),
-		Timeout:   durationInSeconds(args.Int64("timeout")),
+		Out:             os.Stdout,
+		Id:              args.String("fileId"),
+		Force:           args.Bool("force"),
+		Skip:            args.Bool("skip"),
+		Path:            args.String("path"),
+		Delete:          args.Bool("delete"),
+		Permanent:       args.Bool("permanent"),
+		Recursive:       args.Bool("recursive"),
+		NoParent:        args.Bool("noParent"),
+		FollowShortcuts: args.Bool("followShortcuts"),
+		Stdout:          args.Bool("stdout"),
+		Archive:         args.String("archive"),
+		ExportDocs:      args.Bool("exportDocs"),
+		Verify:          args.Bool("verify"),
+		Progress:        progressWriter(args.Bool("noProgress")),
+		Timeout:         durationInSeconds(args.Int64("timeout")),
+		Workers:         int(args.Int64("workers")),
 	})
 	utils.CheckErr(err)
 }
@@ -99,13 +126,16 @@ func DownloadHandler(ctx cli.Context) {
 func DownloadQueryHandler(ctx cli.Context) {
 	args := ctx.Args()
 	err := newDrive(args).DownloadQuery(drive.DownloadQueryArgs{
-		Out:       os.Stdout,
-		Query:     args.String("query"),
-		Force:     args.Bool("force"),
-		Skip:      args.Bool("skip"),
-		Recursive: args.Bool("recursive"),
-		Path:      args.String("path"),
-		Progress:  progressWriter(args.Bool("noProgress")),
+		Out:             os.Stdout,
+		Query:           fileQuery(args),
+		Force:           args.Bool("force"),
+		Skip:            args.Bool("skip"),
+		Recursive:       args.Bool("recursive"),
+		FollowShortcuts: args.Bool("followShortcuts"),
+		Verify:          args.Bool("verify"),
+		Path:            args.String("path"),
+		Progress:        progressWriter(args.Bool("noProgress")),
+		Workers:         int(args.Int64("workers")),
 	})
 	utils.CheckErr(err)
 }
@@ -124,6 +154,10 @@ func DownloadSyncHandler(ctx cli.Context) {
 		Timeout:          durationInSeconds(args.Int64("timeout")),
 		Resolution:       conflictResolution(args),
 		Comparer:         drive.NewCachedMd5Comparer(cachePath),
+		Workers:          int(args.Int64("workers")),
+		StateDir:         filepath.Join(configDir, DefaultSyncStateDirName),
+		FullScan:         args.Bool("fullScan"),
+		Verify:           !args.Bool("noVerify"),
 	})
 	utils.CheckErr(err)
 }
@@ -147,35 +181,83 @@ func UploadHandler(ctx cli.Context) {
 	args := ctx.Args()
 	checkUploadArgs(args)
 	err := newDrive(args).Upload(drive.UploadArgs{
+		Out:          os.Stdout,
+		Progress:     progressWriter(args.Bool("noProgress")),
+		Path:         args.String("path"),
+		Name:         args.String("name"),
+		Description:  args.String("description"),
+		Parents:      args.StringSlice("parent"),
+		Mime:         args.String("mime"),
+		Recursive:    args.Bool("recursive"),
+		Archive:      args.String("archive"),
+		Share:        args.Bool("share"),
+		Delete:       args.Bool("delete"),
+		Verify:       args.Bool("verify"),
+		Encrypt:      args.Bool("encrypt"),
+		EncryptNames: args.Bool("encryptNames"),
+		ChunkSize:    args.Int64("chunksize"),
+		Timeout:      durationInSeconds(args.Int64("timeout")),
+		Sessions:     newUploadSessionStore(args),
+	})
+	utils.CheckErr(err)
+}
+
+func ShellHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).Shell(drive.ShellArgs{
+		In:        os.Stdin,
+		Out:       os.Stdout,
+		Progress:  progressWriter(false),
+		ChunkSize: args.Int64("chunksize"),
+		Timeout:   durationInSeconds(args.Int64("timeout")),
+		Sessions:  newUploadSessionStore(args),
+	})
+	utils.CheckErr(err)
+}
+
+func ServeHttpHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).ServeHttp(drive.ServeHttpArgs{
 		Out:         os.Stdout,
-		Progress:    progressWriter(args.Bool("noProgress")),
-		Path:        args.String("path"),
-		Name:        args.String("name"),
-		Description: args.String("description"),
-		Parents:     args.StringSlice("parent"),
-		Mime:        args.String("mime"),
-		Recursive:   args.Bool("recursive"),
-		Share:       args.Bool("share"),
-		Delete:      args.Bool("delete"),
+		RootId:      args.String("root"),
+		Addr:        args.String("addr"),
+		Credentials: args.String("auth"),
+		CacheTtl:    durationInSeconds(args.Int64("cacheTtl")),
+	})
+	utils.CheckErr(err)
+}
+
+func ServeWebdavHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).ServeWebdav(drive.ServeWebdavArgs{
+		Out:         os.Stdout,
+		RootId:      args.String("root"),
+		Addr:        args.String("addr"),
+		Credentials: args.String("auth"),
+		CacheTtl:    durationInSeconds(args.Int64("cacheTtl")),
 		ChunkSize:   args.Int64("chunksize"),
-		Timeout:     durationInSeconds(args.Int64("timeout")),
+		Permanent:   args.Bool("permanent"),
 	})
 	utils.CheckErr(err)
 }
 
 func UploadStdinHandler(ctx cli.Context) {
 	args := ctx.Args()
+	checkEncryptArgs(args)
 	err := newDrive(args).UploadStream(drive.UploadStreamArgs{
-		Out:         os.Stdout,
-		In:          os.Stdin,
-		Name:        args.String("name"),
-		Description: args.String("description"),
-		Parents:     args.StringSlice("parent"),
-		Mime:        args.String("mime"),
-		Share:       args.Bool("share"),
-		ChunkSize:   args.Int64("chunksize"),
-		Timeout:     durationInSeconds(args.Int64("timeout")),
-		Progress:    progressWriter(args.Bool("noProgress")),
+		Out:          os.Stdout,
+		In:           os.Stdin,
+		Name:         args.String("name"),
+		Description:  args.String("description"),
+		Parents:      args.StringSlice("parent"),
+		Mime:         args.String("mime"),
+		Share:        args.Bool("share"),
+		Verify:       args.Bool("verify"),
+		Encrypt:      args.Bool("encrypt"),
+		EncryptNames: args.Bool("encryptNames"),
+		ChunkSize:    args.Int64("chunksize"),
+		Timeout:      durationInSeconds(args.Int64("timeout")),
+		Progress:     progressWriter(args.Bool("noProgress")),
 	})
 	utils.CheckErr(err)
 }
@@ -184,6 +266,7 @@ func UploadSyncHandler(ctx cli.Context) {
 	args := ctx.Args()
 	configDir := getConfigDir(args)
 	cachePath := filepath.Join(configDir, DefaultCacheFileName)
+	checkEncryptArgs(args)
 	err := newDrive(args).UploadSync(drive.UploadSyncArgs{
 		Out:              os.Stdout,
 		Progress:         progressWriter(args.Bool("noProgress")),
@@ -191,27 +274,67 @@ func UploadSyncHandler(ctx cli.Context) {
 		RootId:           args.String("fileId"),
 		DryRun:           args.Bool("dryRun"),
 		DeleteExtraneous: args.Bool("deleteExtraneous"),
+		Permanent:        args.Bool("permanent"),
+		Encrypt:          args.Bool("encrypt"),
+		EncryptNames:     args.Bool("encryptNames"),
+		Verify:           !args.Bool("noVerify"),
 		ChunkSize:        args.Int64("chunksize"),
 		Timeout:          durationInSeconds(args.Int64("timeout")),
 		Resolution:       conflictResolution(args),
 		Comparer:         drive.NewCachedMd5Comparer(cachePath),
+		Workers:          int(args.Int64("workers")),
+		StateDir:         filepath.Join(configDir, DefaultSyncStateDirName),
+		FullScan:         args.Bool("fullScan"),
+		Sessions:         drive.NewUploadSessionStore(filepath.Join(configDir, DefaultUploadSessionsFileName)),
+		Watch:            args.Bool("watch"),
+		WatchDelay:       durationInSeconds(args.Int64("watchDelay")),
+		Reconcile:        durationInSeconds(args.Int64("reconcileInterval")),
+	})
+	utils.CheckErr(err)
+}
+
+func BidirectionalSyncHandler(ctx cli.Context) {
+	args := ctx.Args()
+	configDir := getConfigDir(args)
+	cachePath := filepath.Join(configDir, DefaultCacheFileName)
+	err := newDrive(args).BidirectionalSync(drive.BidirectionalSyncArgs{
+		Out:        os.Stdout,
+		Progress:   progressWriter(args.Bool("noProgress")),
+		Path:       args.String("path"),
+		RootId:     args.String("fileId"),
+		DryRun:     args.Bool("dryRun"),
+		ChunkSize:  args.Int64("chunksize"),
+		Timeout:    durationInSeconds(args.Int64("timeout")),
+		Resolution: conflictResolution(args),
+		Comparer:   drive.NewCachedMd5Comparer(cachePath),
+		Workers:    int(args.Int64("workers")),
+		StateDir:   filepath.Join(configDir, DefaultSyncStateDirName),
+		FullScan:   args.Bool("fullScan"),
+		Permanent:  args.Bool("permanent"),
+		Verify:     !args.Bool("noVerify"),
+		Sessions:   drive.NewUploadSessionStore(filepath.Join(configDir, DefaultUploadSessionsFileName)),
 	})
 	utils.CheckErr(err)
 }
 
 func UpdateHandler(ctx cli.Context) {
 	args := ctx.Args()
+	checkEncryptArgs(args)
 	err := newDrive(args).Update(drive.UpdateArgs{
-		Out:         os.Stdout,
-		Id:          args.String("fileId"),
-		Path:        args.String("path"),
-		Name:        args.String("name"),
-		Description: args.String("description"),
-		Parents:     args.StringSlice("parent"),
-		Mime:        args.String("mime"),
-		Progress:    progressWriter(args.Bool("noProgress")),
-		ChunkSize:   args.Int64("chunksize"),
-		Timeout:     durationInSeconds(args.Int64("timeout")),
+		Out:          os.Stdout,
+		Id:           args.String("fileId"),
+		Path:         args.String("path"),
+		Name:         args.String("name"),
+		Description:  args.String("description"),
+		Parents:      args.StringSlice("parent"),
+		Mime:         args.String("mime"),
+		Progress:     progressWriter(args.Bool("noProgress")),
+		Verify:       args.Bool("verify"),
+		Encrypt:      args.Bool("encrypt"),
+		EncryptNames: args.Bool("encryptNames"),
+		ChunkSize:    args.Int64("chunksize"),
+		Timeout:      durationInSeconds(args.Int64("timeout")),
+		Sessions:     newUploadSessionStore(args),
 	})
 	utils.CheckErr(err)
 }
@@ -222,6 +345,8 @@ func InfoHandler(ctx cli.Context) {
 		Out:         os.Stdout,
 		Id:          args.String("fileId"),
 		SizeInBytes: args.Bool("sizeInBytes"),
+		Output:      outputFormat(args),
+		Format:      args.String("format"),
 	})
 	utils.CheckErr(err)
 }
@@ -258,6 +383,8 @@ func ListRevisionsHandler(ctx cli.Context) {
 		NameWidth:   args.Int64("nameWidth"),
 		SizeInBytes: args.Bool("sizeInBytes"),
 		SkipHeader:  args.Bool("skipHeader"),
+		Output:      outputFormat(args),
+		Format:      args.String("format"),
 	})
 	utils.CheckErr(err)
 }
@@ -292,6 +419,8 @@ func ShareListHandler(ctx cli.Context) {
 	err := newDrive(args).ListPermissions(drive.ListPermissionsArgs{
 		Out:    os.Stdout,
 		FileId: args.String("fileId"),
+		Output: outputFormat(args),
+		Format: args.String("format"),
 	})
 	utils.CheckErr(err)
 }
@@ -312,6 +441,89 @@ func DeleteHandler(ctx cli.Context) {
 		Out:       os.Stdout,
 		Id:        args.String("fileId"),
 		Recursive: args.Bool("recursive"),
+		Permanent: args.Bool("permanent"),
+	})
+	utils.CheckErr(err)
+}
+
+func CreateShortcutHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).CreateShortcut(drive.CreateShortcutArgs{
+		Out:      os.Stdout,
+		TargetId: args.String("targetId"),
+		FolderId: args.String("folderId"),
+		Name:     args.String("name"),
+	})
+	utils.CheckErr(err)
+}
+
+func TrashHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).Trash(drive.TrashArgs{
+		Out: os.Stdout,
+		Id:  args.String("fileId"),
+	})
+	utils.CheckErr(err)
+}
+
+func UntrashHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).Untrash(drive.UntrashArgs{
+		Out: os.Stdout,
+		Id:  args.String("fileId"),
+	})
+	utils.CheckErr(err)
+}
+
+func DedupeHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).Dedupe(drive.DedupeArgs{
+		Out:         os.Stdout,
+		Query:       fileQuery(args),
+		Keep:        args.String("keep"),
+		Trash:       args.Bool("trash"),
+		SkipHeader:  args.Bool("skipHeader"),
+		SizeInBytes: args.Bool("sizeInBytes"),
+		Output:      outputFormat(args),
+	})
+	utils.CheckErr(err)
+}
+
+func DiskUsageHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).DiskUsage(drive.DiskUsageArgs{
+		Out:         os.Stdout,
+		Id:          args.String("fileId"),
+		Depth:       args.Int64("depth"),
+		SkipHeader:  args.Bool("skipHeader"),
+		SizeInBytes: args.Bool("sizeInBytes"),
+		Output:      outputFormat(args),
+	})
+	utils.CheckErr(err)
+}
+
+func ListTrashHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).ListTrash(drive.ListTrashArgs{
+		Out:         os.Stdout,
+		MaxFiles:    args.Int64("maxFiles"),
+		NameWidth:   args.Int64("nameWidth"),
+		SkipHeader:  args.Bool("skipHeader"),
+		SizeInBytes: args.Bool("sizeInBytes"),
+		Output:      outputFormat(args),
+		Format:      args.String("format"),
+	})
+	utils.CheckErr(err)
+}
+
+func EmptyTrashHandler(ctx cli.Context) {
+	args := ctx.Args()
+	if !args.Bool("force") {
+		utils.ExitF("Emptying the trash permanently deletes all trashed files, use --force to confirm")
+	}
+
+	err := newDrive(args).EmptyTrash(drive.EmptyTrashArgs{
+		Out: os.Stdout,
 	})
 	utils.CheckErr(err)
 }
@@ -321,6 +533,7 @@ func ListSyncHandler(ctx cli.Context) {
 	err := newDrive(args).ListSync(drive.ListSyncArgs{
 		Out:        os.Stdout,
 		SkipHeader: args.Bool("skipHeader"),
+		Output:     outputFormat(args),
 	})
 	utils.CheckErr(err)
 }
@@ -334,6 +547,7 @@ func ListRecursiveSyncHandler(ctx cli.Context) {
 		PathWidth:   args.Int64("pathWidth"),
 		SizeInBytes: args.Bool("sizeInBytes"),
 		SortOrder:   args.String("sortOrder"),
+		Output:      outputFormat(args),
 	})
 	utils.CheckErr(err)
 }
@@ -350,6 +564,18 @@ func DeleteRevisionHandler(ctx cli.Context) {
 
 func AboutHandler(ctx cli.Context) {
 	args := ctx.Args()
+	output := outputFormat(args)
+
+	// Structured output only contains the about record
+	if output != drive.TableOutput {
+		err := newDrive(args).About(drive.AboutArgs{
+			Out:    os.Stdout,
+			Output: output,
+		})
+		utils.CheckErr(err)
+		return
+	}
+
 	printAboutHeader()
 	fmt.Println("")
 
@@ -452,14 +678,25 @@ func newDrive(args cli.Arguments) *drive.Drive {
 		utils.ExitF("Failed getting oauth client: %s", err.Error())
 	}
 
-	client, err := drive.New(oauth)
+	client, err := drive.New(oauth, args.String("driveId"))
 	if err != nil {
 		utils.ExitF("Failed getting drive: %s", err.Error())
 	}
 
+	key, err := drive.LoadEncryptionKey(args.String("keyFile"), os.Getenv(drive.PassphraseEnv), filepath.Join(getConfigDir(args), DefaultEncryptionSaltFileName))
+	if err != nil {
+		utils.ExitF("%s", err)
+	}
+	client.SetEncryptionKey(key)
+
 	return client
 }
 
+func newUploadSessionStore(args cli.Arguments) *drive.UploadSessionStore {
+	configDir := getConfigDir(args)
+	return drive.NewUploadSessionStore(filepath.Join(configDir, DefaultUploadSessionsFileName))
+}
+
 func authCodePrompt(url string) func() string {
 	return func() string {
 		fmt.Println("")
@@ -501,6 +738,47 @@ func durationInSeconds(seconds int64) time.Duration {
 	return time.Second * time.Duration(seconds)
 }
 
+func changesStateFile(args cli.Arguments) string {
+	if path := args.String("stateFile"); path != "" {
+		return path
+	}
+	return filepath.Join(getConfigDir(args), DefaultChangesStateFileName)
+}
+
+func outputFormat(args cli.Arguments) drive.OutputFormat {
+	format, err := drive.ParseOutputFormat(args.String("output"))
+	if err != nil {
+		utils.ExitF("%s", err)
+	}
+	return format
+}
+
+func fileQuery(args cli.Arguments) drive.FileQuery {
+	var largerThan int64
+	if size := args.String("largerThan"); size != "" {
+		var err error
+		largerThan, err = utils.ParseSize(size)
+		if err != nil {
+			utils.ExitF("%s", err)
+		}
+	}
+
+	return drive.FileQuery{
+		Query:         args.String("query"),
+		Name:          args.String("name"),
+		NameContains:  args.String("nameContains"),
+		Parent:        args.String("parent"),
+		Type:          args.String("type"),
+		ModifiedAfter: args.String("modifiedAfter"),
+		LargerThan:    largerThan,
+		Starred:       args.Bool("starred"),
+		SharedWithMe:  args.Bool("sharedWithMe"),
+		Trashed:       args.Bool("trashed"),
+		FullText:      args.String("fullText"),
+		Owner:         args.String("owner"),
+	}
+}
+
 func conflictResolution(args cli.Arguments) drive.ConflictResolution {
 	keepLocal := args.Bool("keepLocal")
 	keepRemote := args.Bool("keepRemote")
@@ -533,10 +811,30 @@ func checkUploadArgs(args cli.Arguments) {
 	if args.Bool("recursive") && args.Bool("share") {
 		utils.ExitF("--share is not allowed for recursive uploads")
 	}
+
+	if args.String("archive") != "" && args.Bool("delete") {
+		utils.ExitF("--delete is not allowed for archive uploads")
+	}
+
+	checkEncryptArgs(args)
+}
+
+func checkEncryptArgs(args cli.Arguments) {
+	if args.Bool("encryptNames") && !args.Bool("encrypt") {
+		utils.ExitF("--encrypt-names requires --encrypt")
+	}
 }
 
 func checkDownloadArgs(args cli.Arguments) {
 	if args.Bool("recursive") && args.Bool("delete") {
 		utils.ExitF("--delete is not allowed for recursive downloads")
 	}
+
+	if args.String("archive") != "" && !args.Bool("recursive") {
+		utils.ExitF("--archive requires --recursive")
+	}
+
+	if args.Bool("exportDocs") && args.String("archive") == "" {
+		utils.ExitF("--export-docs requires --archive")
+	}
 }
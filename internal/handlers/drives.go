@@ -14,6 +14,7 @@ func DrivesListHandler(ctx cli.Context) {
 		Out:            os.Stdout,
 		SkipHeader:     args.Bool("skipHeader"),
 		FieldSeparator: args.String("fieldSeparator"),
+		Output:         outputFormat(args),
 	})
 	utils.CheckErr(err)
 }
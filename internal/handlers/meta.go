@@ -57,6 +57,10 @@ func PermissionsHelpHandler(ctx cli.Context) {
 	printScopedHelp(ctx, []string{"permissions"})
 }
 
+func ServeHelpHandler(ctx cli.Context) {
+	printScopedHelp(ctx, []string{"serve"})
+}
+
 func FilesSubcommandHelpHandler(ctx cli.Context) {
 	args := ctx.Args()
 	printCommandPrefixHelp(ctx, "files", args.String("subcommand"))
@@ -76,6 +80,24 @@ func FilesRevisionSubcommandHelpHandler(ctx cli.Context) {
 	printCommandPrefixHelp(ctx, "files", "revision", args.String("subcommand"))
 }
 
+func FilesTrashSubcommandHelpHandler(ctx cli.Context) {
+	args := ctx.Args()
+	printCommandPrefixHelp(ctx, "files", "trash", args.String("subcommand"))
+}
+
+func FilesShortcutHelpHandler(ctx cli.Context) {
+	printScopedHelp(ctx, []string{"files", "shortcut"})
+}
+
+func FilesShortcutSubcommandHelpHandler(ctx cli.Context) {
+	args := ctx.Args()
+	printCommandPrefixHelp(ctx, "files", "shortcut", args.String("subcommand"))
+}
+
+func FilesTrashHelpHandler(ctx cli.Context) {
+	printScopedHelp(ctx, []string{"files", "trash"})
+}
+
 func printCommandPrefixHelp(ctx cli.Context, prefix ...string) {
 	handler := getHandler(ctx.Handlers(), prefix)
 
@@ -83,6 +105,10 @@ func printCommandPrefixHelp(ctx cli.Context, prefix ...string) {
 		utils.ExitF("Command not found")
 	}
 
+	printHandlerHelp(handler)
+}
+
+func printHandlerHelp(handler *cli.Handler) {
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, 0, 0, 3, ' ', 0)
 
@@ -115,6 +141,12 @@ func printScopedHelp(ctx cli.Context, prefix []string) {
 		return
 	}
 
+	// Commands like trash are both a command and a group of commands
+	if handler := getExactHandler(ctx.Handlers(), prefix); handler != nil {
+		printHandlerHelp(handler)
+		fmt.Println("")
+	}
+
 	printCommandList(prefix, node, false)
 }
 
@@ -267,22 +299,28 @@ func containsKey(children map[string]*commandNode, name string) bool {
 
 func commandOrder(prefix []string) []string {
 	if len(prefix) == 0 {
-		return []string{"about", "account", "drives", "files", "permissions", "version", "help"}
+		return []string{"about", "account", "drives", "files", "permissions", "serve", "shell", "version", "help"}
 	}
 
 	switch prefix[len(prefix)-1] {
 	case "account":
 		return []string{"add", "list", "current", "switch", "remove", "export", "import"}
 	case "files":
-		return []string{"list", "download", "upload", "update", "info", "mkdir", "rename", "move", "copy", "delete", "import", "export", "changes", "sync", "revision"}
+		return []string{"list", "download", "upload", "update", "info", "mkdir", "rename", "move", "copy", "shortcut", "delete", "trash", "untrash", "dedupe", "du", "import", "export", "changes", "sync", "revision"}
 	case "permissions":
 		return []string{"share", "list", "revoke"}
 	case "drives":
 		return []string{"list"}
 	case "sync":
-		return []string{"list", "content", "download", "upload"}
+		return []string{"list", "content", "download", "upload", "both"}
 	case "revision":
 		return []string{"list", "download", "delete"}
+	case "trash":
+		return []string{"list", "empty"}
+	case "shortcut":
+		return []string{"create"}
+	case "serve":
+		return []string{"http", "webdav"}
 	default:
 		return nil
 	}
@@ -294,6 +332,8 @@ func groupDescription(name string) string {
 		return "Commands for syncing files"
 	case "revision":
 		return "Commands for managing file revisions"
+	case "shortcut":
+		return "Commands for managing shortcuts"
 	default:
 		return ""
 	}
@@ -311,6 +351,10 @@ func topLevelDescription(name string) string {
 		return "Commands for managing files"
 	case "permissions":
 		return "Commands for managing file permissions"
+	case "serve":
+		return "Commands for serving drive folders"
+	case "shell":
+		return "Start an interactive shell"
 	case "version":
 		return "Print version information"
 	case "help":
@@ -336,6 +380,17 @@ func getHandler(handlers []*cli.Handler, prefix []string) *cli.Handler {
 	return nil
 }
 
+// Returns the handler whose command is exactly the given prefix
+func getExactHandler(handlers []*cli.Handler, prefix []string) *cli.Handler {
+	for _, h := range handlers {
+		if utils.Equal(prefix, literalTokens(h)) {
+			return h
+		}
+	}
+
+	return nil
+}
+
 // Strip optional groups (<...>) from pattern
 func stripOptionals(pattern []string) []string {
 	newArgs := []string{}
@@ -9,6 +9,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -147,6 +149,27 @@ func FormatSize(bytes int64, forceBytes bool) string {
 	return fmt.Sprintf("%.1f %s", value, units[i])
 }
 
+// Parses sizes like 500, 500K, 10MB or 1.5G, units are powers of 1000 like in FormatSize
+func ParseSize(s string) (int64, error) {
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+
+	var multiplier float64 = 1
+	for i, unit := range []string{"K", "M", "G", "T", "P"} {
+		if strings.HasSuffix(value, unit) {
+			value = strings.TrimSuffix(value, unit)
+			multiplier = math.Pow(1000, float64(i+1))
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid size '%s'", s)
+	}
+
+	return int64(n * multiplier), nil
+}
+
 func CalcRate(bytes int64, start, end time.Time) int64 {
 	seconds := float64(end.Sub(start).Seconds())
 	if seconds < 1.0 {